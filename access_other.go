@@ -0,0 +1,29 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package argparse
+
+import (
+	"os"
+)
+
+// accessible returns whether path can be accessed with the given mode, a
+// combination of accessRead, accessWrite and accessExec. As access(2) is not
+// supported on this platform, the permission bits are checked instead, where
+// each access needs to be permitted for any class of users.
+func accessible(path string, mode uint32) bool {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	perm := uint32(fi.Mode().Perm())
+	for _, m := range []uint32{accessRead, accessWrite, accessExec} {
+		if mode&m != 0 && perm&(m<<6|m<<3|m) == 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,18 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package argparse
+
+import (
+	"syscall"
+)
+
+// accessible returns whether path can be accessed with the given mode, a
+// combination of accessRead, accessWrite and accessExec, using access(2)
+// rather than opening it, which may block, e.g. for a FIFO.
+func accessible(path string, mode uint32) bool {
+	return syscall.Access(path, mode) == nil
+}
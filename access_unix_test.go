@@ -0,0 +1,29 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package argparse
+
+import (
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestAccessibleFIFO(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fifo")
+	if err := syscall.Mkfifo(path, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewArgParser("testprog")
+	var a, b string
+	p.StringVar(&a, "a-test", "", "usage-a")
+	p.StringAllowReadable(&a, "a-test")
+	p.StringVar(&b, "b-test", "", "usage-b")
+	p.StringAllowWritable(&b, "b-test")
+	args := []string{"--a-test", path, "--b-test", path}
+	testNoError(t, p.ParseArgs(args))
+}
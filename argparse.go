@@ -7,11 +7,15 @@
 package argparse
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"regexp"
 	"slices"
 	"strings"
+	"text/template"
 
 	"github.com/spf13/pflag"
 )
@@ -22,40 +26,259 @@ type ArgParser struct {
 	pflag.FlagSet
 	Error              error
 	Name               string
+	Description        string // displayed in help, below the usage line
+	Epilog             string // displayed at the end of help
+	defaultTexts       map[string]string
 	allowedRegexps     []allowedRegexp
 	allowedOptions     []allowedOption
+	allowedIntRanges   []allowedIntRange
+	allowedFloatRanges []allowedFloat64Range
+	allowedFloatFuncs  []allowedFloat64Func
+	allowedFuncs       []allowedFunc
+	allowedIntFuncs    []allowedIntFunc
+	allowedSliceOpts   []allowedSliceOption
+	allowedSliceRegexp []allowedSliceRegexp
+	advanced           []string
+	allErrors          bool
+	deniedOptions      []deniedOption
+	deniedRegexps      []deniedRegexp
+	atLeastOnes        [][]string
+	attached           []attachedValidator
+	autoEnv            bool
+	autoEnvPrefix      string
+	cliArgs            []cliArg
+	completionHints    map[string]string
+	completions        map[string]func(string) []Candidate
+	configFiles        []configFile
+	configKeys         map[string]string
+	customSources      []ValueSource
+	dependsOns         []dependsOn
+	dotEnv             map[string]string
+	dotEnvFiles        []string
+	envs               map[string][]string
+	errOutput          io.Writer
+	errorHandling      ErrorHandling
+	errorUsage         ErrorUsage
+	errorFormatter     func(error) string
+	exactlyOnes        [][]string
+	examples           []HelpExample
+	exitFunc           func(int)
+	expandEnvAll       bool
+	expandEnvs         []string
+	exitOnHelp         bool
+	flagPriorities     map[string]int
+	flagSections       []flagSection
+	help               helpValue
+	helpTemplate       *template.Template
+	helpWidth          int
+	output             io.Writer
 	pos                []pos
+	precedence         []Source
 	posN               *posN
+	messages           Messages
 	mutuallyExclusives [][]string
+	noAutoEnvs         []string
+	onChanges          []onChange
 	required           []string
+	requiredIfs        []requiredIf
+	requiredTogethers  [][]string
+	responseFiles      bool
+	transforms         []transform
+	usages             []string
+	validateAfter      []func(*ArgParser) error
+	valueSources       map[string]Source
+}
+
+// DefinitionError is the panic value of the methods defining arguments and
+// their constraints when misused, such as when defining an argument twice.
+// See Define(). Note that the panic value was a string before
+// DefinitionError was introduced, so code recovering these panics and
+// asserting a string needs to use errors.As() instead.
+type DefinitionError struct {
+	msg string
+}
+
+func (e *DefinitionError) Error() string {
+	return e.msg
+}
+
+type allowedFloat64Func struct {
+	name   string
+	target *float64
+	fn     func(float64) error
+	hint   string // the hint.* and constraint.* message ID suffix, if any
+}
+
+func (a *allowedFloat64Func) check(p *ArgParser) error {
+	if err := a.fn(*a.target); err != nil {
+		return fmt.Errorf("%s: %w", a.name, p.localize(err))
+	}
+	return nil
+}
+
+type allowedFloat64Range struct {
+	name      string
+	target    *float64
+	min       float64
+	max       float64
+	exclusive bool
+}
+
+func (a *allowedFloat64Range) check(p *ArgParser) error {
+	if a.exclusive {
+		if *a.target <= a.min || *a.target >= a.max {
+			return fmt.Errorf(
+				"%s: %w", a.name, p.errorf("value.range-exclusive", *a.target, a.min, a.max),
+			)
+		}
+	} else if *a.target < a.min || *a.target > a.max {
+		return fmt.Errorf("%s: %w", a.name, p.errorf("value.range", *a.target, a.min, a.max))
+	}
+	return nil
+}
+
+type allowedFunc struct {
+	name   string
+	target *string
+	fn     func(string) error
+}
+
+func (a *allowedFunc) check(p *ArgParser) error {
+	if err := a.fn(*a.target); err != nil {
+		return fmt.Errorf("%s: %w", a.name, p.localize(err))
+	}
+	return nil
+}
+
+type allowedIntFunc struct {
+	name   string
+	target *int
+	fn     func(int) error
+	hint   string // the hint.* and constraint.* message ID suffix, if any
+}
+
+func (a *allowedIntFunc) check(p *ArgParser) error {
+	if err := a.fn(*a.target); err != nil {
+		return fmt.Errorf("%s: %w", a.name, p.localize(err))
+	}
+	return nil
+}
+
+type allowedIntRange struct {
+	name   string
+	target *int
+	min    int
+	max    int
+}
+
+func (a *allowedIntRange) check(p *ArgParser) error {
+	if *a.target < a.min || *a.target > a.max {
+		return fmt.Errorf("%s: %w", a.name, p.errorf("value.range", *a.target, a.min, a.max))
+	}
+	return nil
 }
 
 type allowedOption struct {
+	name        string
+	target      *string
+	options     []string
+	fold        bool
+	optionsFunc func() []string
+}
+
+func (a *allowedOption) check(p *ArgParser) error {
+	options := a.options
+	if a.optionsFunc != nil {
+		options = a.optionsFunc()
+	}
+	if a.fold {
+		for _, option := range options {
+			if strings.EqualFold(option, *a.target) {
+				*a.target = option
+				return nil
+			}
+		}
+	}
+	if err := checkOptions(*a.target, options); err != nil {
+		err = fmt.Errorf("%s: %w", a.name, p.localize(err))
+		return &ChoiceError{a.name, *a.target, options, err}
+	}
+	return nil
+}
+
+type allowedRegexp struct {
+	name   string
+	target *string
+	regexp *regexp.Regexp
+}
+
+func (a *allowedRegexp) check(p *ArgParser) error {
+	if !a.regexp.MatchString(*a.target) {
+		return fmt.Errorf("%s: %w", a.name, p.errorf("value.regexp", *a.target, a.regexp))
+	}
+	return nil
+}
+
+type allowedSliceOption struct {
+	name    string
+	target  *[]string
+	options []string
+}
+
+func (a *allowedSliceOption) check(p *ArgParser) error {
+	for i := range *a.target {
+		elem := allowedOption{fmt.Sprintf("%s[%d]", a.name, i), &(*a.target)[i], a.options, false, nil}
+		if err := elem.check(p); err != nil {
+			err.(*ChoiceError).Flag = a.name
+			return err
+		}
+	}
+	return nil
+}
+
+type allowedSliceRegexp struct {
+	name   string
+	target *[]string
+	regexp *regexp.Regexp
+}
+
+func (a *allowedSliceRegexp) check(p *ArgParser) error {
+	for i := range *a.target {
+		elem := allowedRegexp{fmt.Sprintf("%s[%d]", a.name, i), &(*a.target)[i], a.regexp}
+		if err := elem.check(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type dependsOn struct {
+	name      string
+	otherName string
+}
+
+type deniedOption struct {
 	name    string
 	target  *string
 	options []string
 }
 
-func (a *allowedOption) check() error {
-	if !slices.Contains(a.options, *a.target) {
-		return fmt.Errorf(
-			"%s: invalid value: %q is not among options: %q", a.name, *a.target, a.options,
-		)
+func (d *deniedOption) check(p *ArgParser) error {
+	if slices.Contains(d.options, *d.target) {
+		return fmt.Errorf("%s: %w", d.name, p.errorf("value.denied", *d.target))
 	}
 	return nil
 }
 
-type allowedRegexp struct {
+type deniedRegexp struct {
 	name   string
 	target *string
 	regexp *regexp.Regexp
 }
 
-func (a *allowedRegexp) check() error {
-	if !a.regexp.MatchString(*a.target) {
-		return fmt.Errorf(
-			"%s: invalid value: %q is not matching regexp %q", a.name, *a.target, a.regexp,
-		)
+func (d *deniedRegexp) check(p *ArgParser) error {
+	if d.regexp.MatchString(*d.target) {
+		return fmt.Errorf("%s: %w", d.name, p.errorf("value.denied-regexp", *d.target, d.regexp))
 	}
 	return nil
 }
@@ -74,21 +297,170 @@ type posN struct {
 	maxN   int
 }
 
-// Initializes ArgParser and adds the -h/--help argument.
-func NewArgParser(name string) *ArgParser {
+type requiredIf struct {
+	name       string
+	otherName  string
+	otherValue string
+	anyValue   bool
+}
+
+func (r *requiredIf) check(p *ArgParser) error {
+	other := p.Lookup(r.otherName)
+	if !other.Changed || p.Lookup(r.name).Changed {
+		return nil
+	}
+	if r.anyValue {
+		return &RequiredError{[]string{r.name}, p.errorf("error.required-if-set", r.name, r.otherName)}
+	}
+	if other.Value.String() != r.otherValue {
+		return nil
+	}
+	err := p.errorf("error.required-if", r.name, r.otherName, r.otherValue)
+	return &RequiredError{[]string{r.name}, err}
+}
+
+type transform struct {
+	target *string
+	fn     func(string) string
+}
+
+// Initializes ArgParser and adds the -h/--help argument. errorHandling
+// optionally defines how ParseArgs() handles errors, by default
+// ContinueOnError.
+func NewArgParser(name string, errorHandling ...ErrorHandling) *ArgParser {
 	p := ArgParser{
-		Name: name,
+		Name:       name,
+		exitOnHelp: true,
+	}
+	if len(errorHandling) > 1 {
+		p.die("multiple error handling modes given: %v", errorHandling)
+	} else if len(errorHandling) == 1 {
+		p.errorHandling = errorHandling[0]
 	}
 	p.Init(name, pflag.ContinueOnError)
-	p.BoolP(
+	flag := p.VarPF(
+		&p.help,
 		"help",
 		"h",
-		false,
-		"display this help text and exit",
+		p.message("help.flag"),
 	)
+	flag.NoOptDefVal = "true"
 	return &p
 }
 
+// AllowFunc defines that the given argument's value is accepted by fn, which
+// returns an error describing why a value is invalid. The error is returned
+// by ParseArgs(), prefixed with the argument name.
+func (p *ArgParser) AllowFunc(target *string, name string, fn func(string) error) {
+	p.checkAllowTarget("allow func", name, "string")
+	if fn == nil {
+		p.die("allow func: %s: cannot be defined with nil func", name)
+	}
+	p.allowedFuncs = append(p.allowedFuncs, allowedFunc{name, target, fn})
+}
+
+// AtLeastOneRequired defines that at least one of the given arguments is
+// required. Enforced with ParseArgs().
+func (p *ArgParser) AtLeastOneRequired(names ...string) {
+	for _, name := range names {
+		if flag := p.Lookup(name); flag == nil {
+			p.die("at least one required: undefined flag: %s", name)
+		}
+	}
+	if len(names) < 2 {
+		p.die("at least one required: %v: at least two flags are needed", names)
+	}
+	if p.Parsed() {
+		p.die("at least one required: %v: cannot define post-parse", names)
+	}
+	p.atLeastOnes = append(p.atLeastOnes, names)
+}
+
+// Define calls fn, which defines arguments and their constraints, and returns
+// the DefinitionError of the first misuse of a definition method rather than
+// panicking, e.g. for definitions built from user-provided data. This includes
+// the string panics of the embedded FlagSet, such as when a flag or shorthand
+// is redefined, which are not printed either. After an error, the parser may
+// be partially defined and should be discarded.
+func (p *ArgParser) Define(fn func()) (err error) {
+	p.FlagSet.SetOutput(io.Discard)
+	defer func() {
+		p.FlagSet.SetOutput(p.errOutput)
+		switch r := recover().(type) {
+		case nil:
+		case *DefinitionError:
+			err = r
+		case string:
+			err = &DefinitionError{fmt.Sprintf("%s: %s", p.Name, r)}
+		default:
+			panic(r)
+		}
+	}()
+	fn()
+	return nil
+}
+
+// DependsOn defines that the given argument can only be given together with
+// the other argument, e.g. a format flag that only applies when verbose output
+// is enabled. Enforced with ParseArgs().
+func (p *ArgParser) DependsOn(name, otherName string) {
+	for _, n := range []string{name, otherName} {
+		if flag := p.Lookup(n); flag == nil {
+			p.die("depends on: undefined flag: %s", n)
+		}
+	}
+	if name == otherName {
+		p.die("depends on: %s: cannot depend on itself", name)
+	}
+	if p.Parsed() {
+		p.die("depends on: %s: cannot define post-parse", name)
+	}
+	p.dependsOns = append(p.dependsOns, dependsOn{name, otherName})
+}
+
+// ExactlyOneRequired defines that exactly one of the given arguments is
+// required, i.e. they are mutually exclusive but one of them must be given.
+// Enforced with ParseArgs().
+func (p *ArgParser) ExactlyOneRequired(names ...string) {
+	for _, name := range names {
+		if flag := p.Lookup(name); flag == nil {
+			p.die("exactly one required: undefined flag: %s", name)
+		}
+	}
+	if len(names) < 2 {
+		p.die("exactly one required: %v: at least two flags are needed", names)
+	}
+	if p.Parsed() {
+		p.die("exactly one required: %v: cannot define post-parse", names)
+	}
+	p.exactlyOnes = append(p.exactlyOnes, names)
+}
+
+// Float64AllowRange defines that the given float64 argument's value is within
+// the range min to max, inclusive. Use math.Inf(-1) as min or math.Inf(1) as
+// max for a range that is open-ended in that direction. Enforced with
+// ParseArgs().
+func (p *ArgParser) Float64AllowRange(target *float64, name string, min, max float64) {
+	p.float64AllowRange("allow range", target, name, min, max, false)
+}
+
+// Float64AllowRangeExclusive is like Float64AllowRange, but the value must not
+// be equal to min or max. A range with only one exclusive bound is defined by
+// combining this with Float64AllowRange using an infinite bound.
+func (p *ArgParser) Float64AllowRangeExclusive(target *float64, name string, min, max float64) {
+	p.float64AllowRange("allow range exclusive", target, name, min, max, true)
+}
+
+// IntAllowRange defines that the given int argument's value is within the
+// range min to max, inclusive. Enforced with ParseArgs().
+func (p *ArgParser) IntAllowRange(target *int, name string, min, max int) {
+	p.checkAllowTarget("allow range", name, "int")
+	if min > max {
+		p.die("allow range: %s: min(%d) > max(%d)", name, min, max)
+	}
+	p.allowedIntRanges = append(p.allowedIntRanges, allowedIntRange{name, target, min, max})
+}
+
 // MutuallyExlusive defines the given arguments as mutually exlusive, i.e. only
 // one of the arguments are allowed simultaneously. Enforced with ParseArgs().
 func (p *ArgParser) MutuallyExclusive(names ...string) {
@@ -110,27 +482,92 @@ func (p *ArgParser) ParseCurrentArgs() error {
 
 // ParseArgs calls FlagSet's Parse(), parsing arguments as usual. Positional
 // arguments and checks such as required arguments are verified afterwards.
+// Errors are handled as defined by the ErrorHandling given to NewArgParser().
+// Completion candidates are written instead when requested by the completion
+// scripts, see GenBashCompletion().
 func (p *ArgParser) ParseArgs(args []string) error {
-	if err := p.Parse(args); err != nil {
-		p.Error = err
+	err := p.parseArgs(args)
+	if err == nil || errors.Is(err, ErrHelp) || errors.Is(err, ErrComplete) {
 		return err
 	}
-	if help, _ := p.GetBool("help"); help {
-		p.generateHelp()
+	if p.errorHandling == ExitOnError || p.errorUsage > ErrorUsageNone {
+		p.printError(err)
 	}
-	if err := p.parseNargs(); err != nil {
-		return err
+	switch p.errorHandling {
+	case ExitOnError:
+		p.exit(2)
+	case PanicOnError:
+		panic(err)
+	}
+	return err
+}
+
+// parseArgs parses the arguments, see ParseArgs().
+func (p *ArgParser) parseArgs(args []string) error {
+	if len(args) > 0 && args[0] == completeArg {
+		p.complete(args[1:])
+		p.exit(0)
+		return ErrComplete
 	}
-	if err := p.parseRequired(); err != nil {
+	if p.responseFiles {
+		var err error
+		if args, err = p.expandResponseFiles(args, nil); err != nil {
+			p.Error = err
+			return err
+		}
+	}
+	p.cliArgs = nil
+	err := p.ParseAll(args, func(flag *pflag.Flag, value string) error {
+		p.cliArgs = append(p.cliArgs, cliArg{flag.Name, value})
+		return p.Set(flag.Name, value)
+	})
+	if err != nil {
+		p.Error = err
 		return err
 	}
-	if err := p.parseMutuallyExclusive(); err != nil {
+	if p.help.help || p.help.advanced {
+		if !p.exitOnHelp {
+			return ErrHelp
+		}
+		fmt.Fprint(p.out(), p.requestedHelp(p.width(p.out())))
+		p.exit(0)
+		return ErrHelp
+	}
+	return p.parseValues()
+}
+
+// parseValues resolves the flag values from the sources and enforces the
+// checks, following the parsing of the command line.
+func (p *ArgParser) parseValues() error {
+	if err := p.parseSources(); err != nil {
 		return err
 	}
-	if err := p.parseAllowed(); err != nil {
+	errs := p.checkErrors()
+	if errs.add(p.parseNargs()) {
+		return errs.err()
+	}
+	p.parseExpandEnv()
+	p.parseTransform()
+	checks := []func() error{
+		p.parseRequired,
+		p.parseRequiredIf,
+		p.parseRequiredTogether,
+		p.parseAtLeastOneRequired,
+		p.parseDependsOn,
+		p.parseMutuallyExclusive,
+		p.parseExactlyOneRequired,
+		p.parseAllowed,
+		p.parseAttached,
+	}
+	for _, check := range checks {
+		if errs.add(check()) {
+			return errs.err()
+		}
+	}
+	if err := errs.err(); err != nil {
 		return err
 	}
-	return nil
+	return p.parseValidateAfter()
 }
 
 // Required sets the given argument as required. Enforced with ParseArgs().
@@ -144,71 +581,94 @@ func (p *ArgParser) Required(name string) {
 	p.required = append(p.required, name)
 }
 
-// StringAllowOptions defines that the given argument's value is one of the
-// given option values. Enforced with ParseArgs().
-func (p *ArgParser) StringAllowOptions(target *string, name string, options []string) {
-	if name == "" {
-		p.die("allow options: cannot be defined with empty name")
-	}
+// RequiredIf sets the given argument as required when the other argument is
+// given with the value otherValue. Enforced with ParseArgs().
+func (p *ArgParser) RequiredIf(name, otherName, otherValue string) {
+	p.requiredIf("required if", name, otherName, otherValue, false)
+}
 
-	found := false
-	for _, pos := range p.pos {
-		if pos.name == name {
-			found = true
-			break
-		}
-	}
+// RequiredIfSet sets the given argument as required when the other argument
+// is given, regardless of its value. Enforced with ParseArgs().
+func (p *ArgParser) RequiredIfSet(name, otherName string) {
+	p.requiredIf("required if set", name, otherName, "", true)
+}
 
-	if !found {
-		flag := p.Lookup(name)
-		if flag == nil {
-			p.die("allow options: undefined flag: %s", name)
-		}
-		if flag.Value.Type() != "string" {
-			p.die("allow options: %s: flag is not for a string value", name)
-		}
-		if p.Parsed() {
-			p.die("allow options: %s: cannot define post-parse", name)
+// RequiredTogether defines the given arguments as required together, i.e. if
+// one of the arguments is given, all of them are required. Enforced with
+// ParseArgs().
+func (p *ArgParser) RequiredTogether(names ...string) {
+	for _, name := range names {
+		if flag := p.Lookup(name); flag == nil {
+			p.die("required together: undefined flag: %s", name)
 		}
 	}
+	if len(names) < 2 {
+		p.die("required together: %v: at least two flags are needed", names)
+	}
+	if p.Parsed() {
+		p.die("required together: %v: cannot define post-parse", names)
+	}
+	p.requiredTogethers = append(p.requiredTogethers, names)
+}
+
+// StringAllowOptions defines that the given argument's value is one of the
+// given option values. Enforced with ParseArgs(). The options are also the
+// shell completion candidates of the value, see GenBashCompletion().
+func (p *ArgParser) StringAllowOptions(target *string, name string, options []string) {
+	p.checkAllowTarget("allow options", name, "string")
+	p.allowedOptions = append(p.allowedOptions, allowedOption{name, target, options, false, nil})
+}
 
-	p.allowedOptions = append(p.allowedOptions, allowedOption{name, target, options})
+// StringAllowOptionsFold is like StringAllowOptions(), but the value is matched
+// case-insensitively, and replaced with the spelling of the matching option,
+// e.g. "JSON" is accepted and stored as "json". Enforced with ParseArgs().
+func (p *ArgParser) StringAllowOptionsFold(target *string, name string, options []string) {
+	p.checkAllowTarget("allow options fold", name, "string")
+	p.allowedOptions = append(p.allowedOptions, allowedOption{name, target, options, true, nil})
+}
+
+// StringAllowOptionsFunc is like StringAllowOptions(), but the options are
+// returned by fn, which is called by ParseArgs() when the value is checked, and
+// when the value is completed. This allows options that depend on runtime
+// state, e.g. a directory listing.
+func (p *ArgParser) StringAllowOptionsFunc(target *string, name string, fn func() []string) {
+	p.checkAllowTarget("allow options func", name, "string")
+	if fn == nil {
+		p.die("allow options func: %s: cannot be defined with nil func", name)
+	}
+	p.allowedOptions = append(p.allowedOptions, allowedOption{name, target, nil, false, fn})
 }
 
 // StringAllowRegexp defines that the given argument's value matches the given
 // the given regular expression. Enforced with ParseArgs().
 func (p *ArgParser) StringAllowRegexp(target *string, name string, re string) {
-	if name == "" {
-		p.die("allow regexp: cannot be defined with empty name")
-	}
+	p.checkAllowTarget("allow regexp", name, "string")
+	rec, err := regexp.Compile(re)
+	if err != nil {
+		p.die("allow regexp: %s: %v", name, err)
 
-	found := false
-	for _, pos := range p.pos {
-		if pos.name == name {
-			found = true
-			break
-		}
 	}
+	p.allowedRegexps = append(p.allowedRegexps, allowedRegexp{name, target, rec})
+}
 
-	if !found {
-		flag := p.Lookup(name)
-		if flag == nil {
-			p.die("allow regexp: undefined flag: %s", name)
-		}
-		if flag.Value.Type() != "string" {
-			p.die("allow regexp: %s: flag is not for a string value", name)
-		}
-		if p.Parsed() {
-			p.die("allow regexp: %s: cannot define post-parse", name)
-		}
-	}
+// StringDenyOptions defines that the given argument's value is not one of the
+// given values, the inverse of StringAllowOptions(). Enforced with
+// ParseArgs().
+func (p *ArgParser) StringDenyOptions(target *string, name string, values []string) {
+	p.checkAllowTarget("deny options", name, "string")
+	p.deniedOptions = append(p.deniedOptions, deniedOption{name, target, values})
+}
 
+// StringDenyRegexp defines that the given argument's value is not matching the
+// given regular expression, the inverse of StringAllowRegexp(). Enforced with
+// ParseArgs().
+func (p *ArgParser) StringDenyRegexp(target *string, name string, re string) {
+	p.checkAllowTarget("deny regexp", name, "string")
 	rec, err := regexp.Compile(re)
 	if err != nil {
-		p.die("allow regexp: %s: %v", name, err)
-
+		p.die("deny regexp: %s: %v", name, err)
 	}
-	p.allowedRegexps = append(p.allowedRegexps, allowedRegexp{name, target, rec})
+	p.deniedRegexps = append(p.deniedRegexps, deniedRegexp{name, target, rec})
 }
 
 // StringPosNVar defines a variable number of string positional arguments. minN
@@ -216,7 +676,7 @@ func (p *ArgParser) StringAllowRegexp(target *string, name string, re string) {
 // number. minN must be less or equal to maxN, unless maxN is -1, which means
 // that an inifinite number of positional arguments may be supplied.
 func (p *ArgParser) StringPosNVar(target *[]string, name, usage string, minN, maxN int) {
-	prefix := fmt.Sprintf("%s: varying positional argument", p.Name)
+	prefix := "varying positional argument"
 
 	if name == "" {
 		p.die("%s cannot be defined with empty name", prefix)
@@ -237,7 +697,7 @@ func (p *ArgParser) StringPosNVar(target *[]string, name, usage string, minN, ma
 		p.die("%s with minN(%d) > maxN(%d)", prefix, minN, maxN)
 	}
 	if p.posN != nil {
-		p.die("%s when a varying positional argument is already defined: %s", p.posN.name)
+		p.die("%s when a varying positional argument is already defined: %s", prefix, p.posN.name)
 	}
 
 	for _, pos := range p.pos {
@@ -252,7 +712,7 @@ func (p *ArgParser) StringPosNVar(target *[]string, name, usage string, minN, ma
 // StringPosVar defines a required string positional argument. It can be given
 // multiple times to add multiple required string positional arguments.
 func (p *ArgParser) StringPosVar(target *string, name, usage string) {
-	prefix := fmt.Sprintf("%s: positional argument", p.Name)
+	prefix := "positional argument"
 
 	if name == "" {
 		p.die("%s cannot be defined with empty name", prefix)
@@ -273,113 +733,266 @@ func (p *ArgParser) StringPosVar(target *string, name, usage string) {
 	}
 	if p.posN != nil {
 		p.die(
-			"%s cannot be defined when a varying positional argument is already defined: %s",
-			p.Name, p.posN.name,
+			"%s %q cannot be defined when a varying positional argument is already defined: %s",
+			prefix, name, p.posN.name,
 		)
 	}
 
 	p.pos = append(p.pos, pos{target, name, usage})
 }
 
+// StringSliceAllowOptions defines that each element of the given string slice
+// or string array argument's value is one of the given option values. It also
+// applies to a varying positional argument. Enforced with ParseArgs(). The
+// options are also the shell completion candidates of each element.
+func (p *ArgParser) StringSliceAllowOptions(target *[]string, name string, options []string) {
+	p.checkAllowTarget("allow options", name, "stringSlice", "stringArray")
+	p.allowedSliceOpts = append(p.allowedSliceOpts, allowedSliceOption{name, target, options})
+}
+
+// StringSliceAllowRegexp defines that each element of the given string slice
+// or string array argument's value matches the given regular expression. It
+// also applies to a varying positional argument. Enforced with ParseArgs().
+func (p *ArgParser) StringSliceAllowRegexp(target *[]string, name string, re string) {
+	p.checkAllowTarget("allow regexp", name, "stringSlice", "stringArray")
+	rec, err := regexp.Compile(re)
+	if err != nil {
+		p.die("allow regexp: %s: %v", name, err)
+	}
+	p.allowedSliceRegexp = append(p.allowedSliceRegexp, allowedSliceRegexp{name, target, rec})
+}
+
+// Transform defines a function that replaces the given argument's value after
+// parsing, e.g. for trimming whitespace or lowercasing it. Transforms run in
+// ParseArgs() before any checks, so that those see the transformed value.
+func (p *ArgParser) Transform(target *string, name string, fn func(string) string) {
+	p.checkAllowTarget("transform", name, "string")
+	if fn == nil {
+		p.die("transform: %s: cannot be defined with nil func", name)
+	}
+	p.transforms = append(p.transforms, transform{target, fn})
+}
+
+// ValidateAfter adds a function that is run after all other checks in
+// ParseArgs() have passed, for validating relations between arguments such as
+// one value being less than another. Its error is returned by ParseArgs().
+func (p *ArgParser) ValidateAfter(fn func(*ArgParser) error) {
+	if fn == nil {
+		p.die("validate after: cannot be defined with nil func")
+	}
+	if p.Parsed() {
+		p.die("validate after: cannot define post-parse")
+	}
+	p.validateAfter = append(p.validateAfter, fn)
+}
+
+// checkAllowTarget verifies that name refers to a positional argument or a
+// flag of one of the given value types, which may still get value
+// constraints. Varying positional arguments are of type stringSlice.
+func (p *ArgParser) checkAllowTarget(prefix, name string, valueTypes ...string) {
+	if name == "" {
+		p.die("%s: cannot be defined with empty name", prefix)
+	}
+
+	posType := ""
+	for _, pos := range p.pos {
+		if pos.name == name {
+			posType = "string"
+		}
+	}
+	if p.posN != nil && p.posN.name == name {
+		posType = "stringSlice"
+	}
+	if posType != "" {
+		if !slices.Contains(valueTypes, posType) {
+			p.die(
+				"%s: %s: positional argument is not for a %s value",
+				prefix, name, strings.Join(valueTypes, " or "),
+			)
+		}
+		return
+	}
+
+	flag := p.Lookup(name)
+	if flag == nil {
+		p.die("%s: undefined flag: %s", prefix, name)
+	}
+	if !slices.Contains(valueTypes, flag.Value.Type()) {
+		p.die("%s: %s: flag is not for a %s value", prefix, name, strings.Join(valueTypes, " or "))
+	}
+	if p.Parsed() {
+		p.die("%s: %s: cannot define post-parse", prefix, name)
+	}
+}
+
 func (p *ArgParser) die(format string, args ...any) {
 	var new []interface{}
 	new = append(new, p.Name)
 	new = append(new, args...)
-	panic(fmt.Sprintf("%s: "+format, new...))
+	panic(&DefinitionError{fmt.Sprintf("%s: "+format, new...)})
 }
 
-func (p *ArgParser) generateHelp() {
-	posArgs := ""
-	posLen := 0
+func (p *ArgParser) float64AllowRange(
+	prefix string, target *float64, name string, min, max float64, exclusive bool,
+) {
+	p.checkAllowTarget(prefix, name, "float64")
+	if math.IsNaN(min) || math.IsNaN(max) {
+		p.die("%s: %s: min and max must be numbers", prefix, name)
+	}
+	if min > max || (exclusive && min == max) {
+		p.die("%s: %s: empty range with min(%g) and max(%g)", prefix, name, min, max)
+	}
+	p.allowedFloatRanges = append(
+		p.allowedFloatRanges, allowedFloat64Range{name, target, min, max, exclusive},
+	)
+}
 
-	for _, pos := range p.pos {
-		posArgs = posArgs + " " + pos.name
-		if len(pos.name) > posLen {
-			posLen = len(pos.name)
+func (p *ArgParser) parseAllowed() error {
+	errs := p.checkErrors()
+	for _, allowed := range p.allowedRegexps {
+		if errs.add(allowed.check(p)) {
+			return errs.err()
 		}
 	}
-
-	if p.posN != nil {
-		if p.posN.minN == 0 {
-			posArgs = posArgs + " [" + p.posN.name + "]"
+	for _, allowed := range p.allowedOptions {
+		if errs.add(allowed.check(p)) {
+			return errs.err()
+		}
+	}
+	for _, denied := range p.deniedRegexps {
+		if errs.add(denied.check(p)) {
+			return errs.err()
 		}
-		for i := 1; i <= p.posN.minN; i++ {
-			posArgs = posArgs + " " + p.posN.name
+	}
+	for _, denied := range p.deniedOptions {
+		if errs.add(denied.check(p)) {
+			return errs.err()
 		}
-		if p.posN.maxN == -1 {
-			posArgs = posArgs + ".."
-		} else {
-			for i := p.posN.minN; i < p.posN.maxN; i++ {
-				posArgs = posArgs + " " + "[" + p.posN.name
-			}
-			for i := p.posN.minN; i < p.posN.maxN; i++ {
-				posArgs = posArgs + "]"
-			}
+	}
+	for _, allowed := range p.allowedIntRanges {
+		if errs.add(allowed.check(p)) {
+			return errs.err()
 		}
-		if len(p.posN.name) > posLen {
-			posLen = len(p.posN.name)
+	}
+	for _, allowed := range p.allowedFloatRanges {
+		if errs.add(allowed.check(p)) {
+			return errs.err()
 		}
 	}
-
-	fmt.Printf("usage: %s [flag]..%s\n\n", p.Name, posArgs)
-
-	if posLen > 0 {
-		format := fmt.Sprintf("  %%-%ds   %%s\n", posLen)
-		fmt.Printf("positional arguments:\n")
-		for _, pos := range p.pos {
-			fmt.Printf(format, pos.name, pos.usage)
+	for _, allowed := range p.allowedFuncs {
+		if errs.add(allowed.check(p)) {
+			return errs.err()
+		}
+	}
+	for _, allowed := range p.allowedIntFuncs {
+		if errs.add(allowed.check(p)) {
+			return errs.err()
 		}
-		if p.posN != nil {
-			fmt.Printf(format, p.posN.name, p.posN.usage)
+	}
+	for _, allowed := range p.allowedFloatFuncs {
+		if errs.add(allowed.check(p)) {
+			return errs.err()
+		}
+	}
+	for _, allowed := range p.allowedSliceRegexp {
+		if errs.add(allowed.check(p)) {
+			return errs.err()
+		}
+	}
+	for _, allowed := range p.allowedSliceOpts {
+		if errs.add(allowed.check(p)) {
+			return errs.err()
 		}
-		fmt.Printf("\n")
 	}
+	return errs.err()
+}
 
-	fmt.Printf("flags:\n")
-	fmt.Printf("%s", p.FlagUsages())
-	os.Exit(0)
+func (p *ArgParser) parseAtLeastOneRequired() error {
+	errs := p.checkErrors()
+	for _, names := range p.atLeastOnes {
+		found := false
+		for _, name := range names {
+			if p.Lookup(name).Changed {
+				found = true
+				break
+			}
+		}
+		if !found {
+			err := p.errorf("error.at-least-one", strings.Join(names, ", "))
+			if errs.add(&RequiredError{names, err}) {
+				return errs.err()
+			}
+		}
+	}
+	return errs.err()
 }
 
-func (p *ArgParser) parseAllowed() error {
-	for _, allowed := range p.allowedRegexps {
-		if err := allowed.check(); err != nil {
-			return err
+func (p *ArgParser) parseDependsOn() error {
+	errs := p.checkErrors()
+	for _, d := range p.dependsOns {
+		if p.Lookup(d.name).Changed && !p.Lookup(d.otherName).Changed {
+			err := p.errorf("error.depends-on", d.name, d.otherName)
+			if errs.add(&RequiredError{[]string{d.otherName}, err}) {
+				return errs.err()
+			}
 		}
 	}
-	for _, allowed := range p.allowedOptions {
-		if err := allowed.check(); err != nil {
-			return err
+	return errs.err()
+}
+
+func (p *ArgParser) parseExactlyOneRequired() error {
+	errs := p.checkErrors()
+	for _, names := range p.exactlyOnes {
+		var changed []string
+		for _, name := range names {
+			if p.Lookup(name).Changed {
+				changed = append(changed, name)
+			}
+		}
+		var err error
+		if len(changed) > 1 {
+			err = &MutualExclusionError{changed[:2], p.errorf(
+				"error.exactly-one-multiple", changed[0], changed[1], strings.Join(names, ", "),
+			)}
+		} else if len(changed) == 0 {
+			err = &RequiredError{names, p.errorf("error.exactly-one", strings.Join(names, ", "))}
+		}
+		if errs.add(err) {
+			return errs.err()
 		}
 	}
-	return nil
+	return errs.err()
 }
 
 func (p *ArgParser) parseMutuallyExclusive() error {
+	errs := p.checkErrors()
 	for _, names := range p.mutuallyExclusives {
-		changed := ""
+		var changed []string
 		for _, name := range names {
-			flag := p.Lookup(name)
-			if flag.Changed {
-				if changed != "" {
-					return fmt.Errorf("%s and %s are mutually exclusive flags", changed, name)
-				}
-				changed = name
+			if p.Lookup(name).Changed {
+				changed = append(changed, name)
+			}
+		}
+		if len(changed) > 1 {
+			err := p.errorf("error.mutually-exclusive", changed[0], changed[1])
+			if errs.add(&MutualExclusionError{changed[:2], err}) {
+				return errs.err()
 			}
 		}
 	}
-	return nil
+	return errs.err()
 }
 
 func (p *ArgParser) parseNargs() error {
 	nargs := p.Args()
 
 	if len(nargs) > 0 && len(p.pos) == 0 && p.posN == nil {
-		return fmt.Errorf("no positional arguments expected")
+		return p.errorf("error.positionals-none")
 	}
 
 	if len(p.pos) > 0 {
 		if len(nargs) < len(p.pos) {
-			return fmt.Errorf("insufficient number of positional arguments, see --help")
+			return p.errorf("error.positionals-few")
 		}
 		for i, v := range nargs[0:len(p.pos)] {
 			*p.pos[i].target = v
@@ -391,27 +1004,15 @@ func (p *ArgParser) parseNargs() error {
 		if len(nargs) < p.posN.minN {
 			if len(nargs) == 0 {
 				if p.posN.maxN == -1 {
-					return fmt.Errorf(
-						"no %q positional argument(s) provided, see --help",
-						p.posN.name,
-					)
+					return p.errorf("error.posn-missing", p.posN.name)
 				} else {
-					return fmt.Errorf(
-						"no %q positional argument(s) provided, expected %d, see --help",
-						p.posN.name, p.posN.minN,
-					)
+					return p.errorf("error.posn-missing-min", p.posN.name, p.posN.minN)
 				}
 			}
-			return fmt.Errorf(
-				"got %d %q positional argument(s), expected %d at least, see --help",
-				len(nargs), p.posN.name, p.posN.minN,
-			)
+			return p.errorf("error.posn-min", len(nargs), p.posN.name, p.posN.minN)
 		}
 		if p.posN.maxN != -1 && len(nargs) > p.posN.maxN {
-			return fmt.Errorf(
-				"got %d %q positional argument(s), expected %d at most, see --help",
-				len(nargs), p.posN.name, p.posN.maxN,
-			)
+			return p.errorf("error.posn-max", len(nargs), p.posN.name, p.posN.maxN)
 		}
 		*p.posN.target = nargs
 		nargs = nargs[:0]
@@ -433,9 +1034,74 @@ func (p *ArgParser) parseRequired() error {
 		}
 	}
 	if len(required) == 1 {
-		return fmt.Errorf("missing required flag: %s", required[0])
+		return &RequiredError{required, p.errorf("error.required", required[0])}
 	} else if len(required) > 1 {
-		return fmt.Errorf("missing required flags: %s", strings.Join(required, ", "))
+		err := p.errorf("error.required-multiple", strings.Join(required, ", "))
+		return &RequiredError{required, err}
 	}
 	return nil
 }
+
+func (p *ArgParser) parseRequiredIf() error {
+	errs := p.checkErrors()
+	for _, r := range p.requiredIfs {
+		if errs.add(r.check(p)) {
+			return errs.err()
+		}
+	}
+	return errs.err()
+}
+
+func (p *ArgParser) parseRequiredTogether() error {
+	errs := p.checkErrors()
+	for _, names := range p.requiredTogethers {
+		var changed, missing []string
+		for _, name := range names {
+			if p.Lookup(name).Changed {
+				changed = append(changed, name)
+			} else {
+				missing = append(missing, name)
+			}
+		}
+		if len(changed) > 0 && len(missing) > 0 {
+			err := p.errorf(
+				"error.required-together", strings.Join(names, ", "), strings.Join(missing, ", "),
+			)
+			if errs.add(&RequiredError{missing, err}) {
+				return errs.err()
+			}
+		}
+	}
+	return errs.err()
+}
+
+func (p *ArgParser) parseTransform() {
+	for _, t := range p.transforms {
+		*t.target = t.fn(*t.target)
+	}
+}
+
+func (p *ArgParser) parseValidateAfter() error {
+	errs := p.checkErrors()
+	for _, fn := range p.validateAfter {
+		if errs.add(fn(p)) {
+			return errs.err()
+		}
+	}
+	return errs.err()
+}
+
+func (p *ArgParser) requiredIf(prefix, name, otherName, otherValue string, anyValue bool) {
+	for _, n := range []string{name, otherName} {
+		if flag := p.Lookup(n); flag == nil {
+			p.die("%s: undefined flag: %s", prefix, n)
+		}
+	}
+	if name == otherName {
+		p.die("%s: %s: cannot depend on itself", prefix, name)
+	}
+	if p.Parsed() {
+		p.die("%s: %s: cannot define post-parse", prefix, name)
+	}
+	p.requiredIfs = append(p.requiredIfs, requiredIf{name, otherName, otherValue, anyValue})
+}
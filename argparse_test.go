@@ -5,6 +5,9 @@
 package argparse
 
 import (
+	"errors"
+	"math"
+	"strings"
 	"testing"
 )
 
@@ -23,6 +26,239 @@ func testNoError(t *testing.T, err error) {
 	}
 }
 
+func TestAllowFuncFail(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringPosVar(&a, "a", "usage-a")
+	errOdd := errors.New("odd length")
+	p.AllowFunc(&a, "a", func(v string) error {
+		if len(v)%2 != 0 {
+			return errOdd
+		}
+		return nil
+	})
+	args := []string{"abc"}
+	err := p.ParseArgs(args)
+	testError(t, err, "a: odd length")
+	if !errors.Is(err, errOdd) {
+		t.Fatalf("expected error wrapping %v", errOdd)
+	}
+}
+
+func TestAllowFuncOK(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringPosVar(&a, "a", "usage-a")
+	p.AllowFunc(&a, "a", func(v string) error {
+		if len(v)%2 != 0 {
+			return errors.New("odd length")
+		}
+		return nil
+	})
+	args := []string{"ab"}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
+}
+
+func TestAtLeastOneRequiredFail(t *testing.T) {
+	p := NewArgParser("testprog")
+	var a string
+	p.StringVarP(&a, "a-test", "a", "default-a", "usage-a")
+	var b string
+	p.StringVarP(&b, "b-test", "b", "default-b", "usage-b")
+	p.AtLeastOneRequired("a-test", "b-test")
+	args := []string{}
+	err := p.ParseArgs(args)
+	testError(t, err, "at least one of the flags a-test, b-test is required")
+}
+
+func TestAtLeastOneRequiredOK(t *testing.T) {
+	p := NewArgParser("testprog")
+	var a string
+	p.StringVarP(&a, "a-test", "a", "default-a", "usage-a")
+	var b string
+	p.StringVarP(&b, "b-test", "b", "default-b", "usage-b")
+	p.AtLeastOneRequired("a-test", "b-test")
+	args := []string{"-b", "test"}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
+}
+
+func TestDefineFail(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a []string
+	var b []string
+	err := p.Define(func() {
+		p.StringPosNVar(&a, "a-test", "usage-a", 0, -1)
+		p.StringPosNVar(&b, "b-test", "usage-b", 0, -1)
+	})
+	testError(
+		t, err,
+		`testprog: varying positional argument "b-test" cannot be defined `+
+			`when a varying positional argument is already defined: a-test`,
+	)
+	var derr *DefinitionError
+	if !errors.As(err, &derr) {
+		t.Fatalf("expected DefinitionError, got: %T", err)
+	}
+
+	p = NewArgParser("testprog")
+	testError(
+		t, p.Define(func() { p.Required("a-test") }),
+		"testprog: required: undefined flag: a-test",
+	)
+
+	p = NewArgParser("testprog")
+	var out strings.Builder
+	p.SetErrOutput(&out)
+	var c, d string
+	p.StringVarP(&c, "c-test", "c", "", "usage-c")
+	err = p.Define(func() { p.StringVar(&d, "c-test", "", "usage-d") })
+	testError(t, err, "testprog: testprog flag redefined: c-test")
+	if !errors.As(err, &derr) {
+		t.Fatalf("expected DefinitionError, got: %T", err)
+	}
+	testError(
+		t, p.Define(func() { p.StringVarP(&d, "d-test", "c", "", "usage-d") }),
+		`testprog: unable to redefine 'c' shorthand in "testprog" flagset: `+
+			`it's already used for "c-test" flag`,
+	)
+	if out.String() != "" {
+		t.Fatalf("unexpected output: %q", out.String())
+	}
+}
+
+func TestDefineOK(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	testNoError(t, p.Define(func() {
+		p.StringVar(&a, "a-test", "", "usage-a")
+		p.Required("a-test")
+	}))
+	args := []string{"--a-test", "x"}
+	testNoError(t, p.ParseArgs(args))
+}
+
+func TestDependsOnFail(t *testing.T) {
+	p := NewArgParser("testprog")
+	var a string
+	p.StringVarP(&a, "a-test", "a", "default-a", "usage-a")
+	var b bool
+	p.BoolVarP(&b, "b-test", "b", false, "usage-b")
+	p.DependsOn("a-test", "b-test")
+	args := []string{"-a", "test"}
+	err := p.ParseArgs(args)
+	testError(t, err, "flag a-test cannot be used without flag b-test")
+}
+
+func TestDependsOnOK(t *testing.T) {
+	p := NewArgParser("testprog")
+	var a string
+	p.StringVarP(&a, "a-test", "a", "default-a", "usage-a")
+	var b bool
+	p.BoolVarP(&b, "b-test", "b", false, "usage-b")
+	p.DependsOn("a-test", "b-test")
+	args := []string{"-a", "test", "-b"}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
+}
+
+func TestExactlyOneRequiredFailMany(t *testing.T) {
+	p := NewArgParser("testprog")
+	var a string
+	p.StringVarP(&a, "a-test", "a", "default-a", "usage-a")
+	var b string
+	p.StringVarP(&b, "b-test", "b", "default-b", "usage-b")
+	p.ExactlyOneRequired("a-test", "b-test")
+	args := []string{"-a", "test", "-b", "test"}
+	err := p.ParseArgs(args)
+	testError(t, err, "a-test and b-test are mutually exclusive flags, exactly one of a-test, b-test is required")
+}
+
+func TestExactlyOneRequiredFailNone(t *testing.T) {
+	p := NewArgParser("testprog")
+	var a string
+	p.StringVarP(&a, "a-test", "a", "default-a", "usage-a")
+	var b string
+	p.StringVarP(&b, "b-test", "b", "default-b", "usage-b")
+	p.ExactlyOneRequired("a-test", "b-test")
+	args := []string{}
+	err := p.ParseArgs(args)
+	testError(t, err, "exactly one of the flags a-test, b-test is required")
+}
+
+func TestExactlyOneRequiredOK(t *testing.T) {
+	p := NewArgParser("testprog")
+	var a string
+	p.StringVarP(&a, "a-test", "a", "default-a", "usage-a")
+	var b string
+	p.StringVarP(&b, "b-test", "b", "default-b", "usage-b")
+	p.ExactlyOneRequired("a-test", "b-test")
+	args := []string{"-a", "test"}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
+}
+
+func TestFloat64AllowRangeExclusiveFail(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a float64
+	p.Float64VarP(&a, "a-test", "a", 0.5, "usage-a")
+	p.Float64AllowRangeExclusive(&a, "a-test", 0, math.Inf(1))
+	args := []string{"-a", "0"}
+	err := p.ParseArgs(args)
+	testError(t, err, "a-test: invalid value: 0 is not within range 0 to +Inf, exclusive")
+}
+
+func TestFloat64AllowRangeFail(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a float64
+	p.Float64VarP(&a, "a-test", "a", 0.5, "usage-a")
+	p.Float64AllowRange(&a, "a-test", 0, 1)
+	args := []string{"-a", "1.5"}
+	err := p.ParseArgs(args)
+	testError(t, err, "a-test: invalid value: 1.5 is not within range 0 to 1")
+}
+
+func TestFloat64AllowRangeOK(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a float64
+	p.Float64VarP(&a, "a-test", "a", 0.5, "usage-a")
+	p.Float64AllowRangeExclusive(&a, "a-test", 0, math.Inf(1))
+	p.Float64AllowRange(&a, "a-test", math.Inf(-1), 1)
+	args := []string{"-a", "1"}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
+}
+
+func TestIntAllowRangeFail(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a int
+	p.IntVarP(&a, "a-test", "a", 1, "usage-a")
+	p.IntAllowRange(&a, "a-test", 1, 10)
+	args := []string{"-a", "11"}
+	err := p.ParseArgs(args)
+	testError(t, err, "a-test: invalid value: 11 is not within range 1 to 10")
+}
+
+func TestIntAllowRangeOK(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a int
+	p.IntVarP(&a, "a-test", "a", 1, "usage-a")
+	p.IntAllowRange(&a, "a-test", 1, 10)
+	args := []string{"-a", "10"}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
+}
+
 func TestMutuallyExclusiveFail(t *testing.T) {
 	p := NewArgParser("testprog")
 	var a string
@@ -80,6 +316,54 @@ func TestRequiredFail(t *testing.T) {
 	testError(t, err, "missing required flag: b-test")
 }
 
+func TestRequiredIfFail(t *testing.T) {
+	p := NewArgParser("testprog")
+	var a string
+	p.StringVarP(&a, "a-test", "a", "default-a", "usage-a")
+	var b string
+	p.StringVarP(&b, "b-test", "b", "default-b", "usage-b")
+	p.RequiredIf("b-test", "a-test", "test")
+	args := []string{"-a", "test"}
+	err := p.ParseArgs(args)
+	testError(t, err, "missing required flag: b-test, required when a-test is \"test\"")
+}
+
+func TestRequiredIfOK(t *testing.T) {
+	p := NewArgParser("testprog")
+	var a string
+	p.StringVarP(&a, "a-test", "a", "default-a", "usage-a")
+	var b string
+	p.StringVarP(&b, "b-test", "b", "default-b", "usage-b")
+	p.RequiredIf("b-test", "a-test", "test")
+	args := []string{"-a", "other"}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
+}
+
+func TestRequiredIfSetFail(t *testing.T) {
+	p := NewArgParser("testprog")
+	var a bool
+	p.BoolVarP(&a, "a-test", "a", false, "usage-a")
+	var b string
+	p.StringVarP(&b, "b-test", "b", "default-b", "usage-b")
+	p.RequiredIfSet("b-test", "a-test")
+	args := []string{"-a"}
+	err := p.ParseArgs(args)
+	testError(t, err, "missing required flag: b-test, required when a-test is set")
+}
+
+func TestRequiredIfSetOK(t *testing.T) {
+	p := NewArgParser("testprog")
+	var a bool
+	p.BoolVarP(&a, "a-test", "a", false, "usage-a")
+	var b string
+	p.StringVarP(&b, "b-test", "b", "default-b", "usage-b")
+	p.RequiredIfSet("b-test", "a-test")
+	args := []string{"-a", "-b", "test"}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
+}
+
 func TestRequiredOK(t *testing.T) {
 	p := NewArgParser("testprog")
 	var a string
@@ -92,6 +376,37 @@ func TestRequiredOK(t *testing.T) {
 	testNoError(t, err)
 }
 
+func TestRequiredTogetherFail(t *testing.T) {
+	p := NewArgParser("testprog")
+	var a string
+	p.StringVarP(&a, "a-test", "a", "default-a", "usage-a")
+	var b string
+	p.StringVarP(&b, "b-test", "b", "default-b", "usage-b")
+	var c string
+	p.StringVarP(&c, "c-test", "c", "default-c", "usage-c")
+	p.RequiredTogether("a-test", "b-test", "c-test")
+	args := []string{"-b", "test"}
+	err := p.ParseArgs(args)
+	testError(t, err, "flags a-test, b-test, c-test are required together, missing: a-test, c-test")
+}
+
+func TestRequiredTogetherOK(t *testing.T) {
+	p := NewArgParser("testprog")
+	var a string
+	p.StringVarP(&a, "a-test", "a", "default-a", "usage-a")
+	var b string
+	p.StringVarP(&b, "b-test", "b", "default-b", "usage-b")
+	p.RequiredTogether("a-test", "b-test")
+	err := p.ParseArgs([]string{})
+	testNoError(t, err)
+	p = NewArgParser("testprog")
+	p.StringVarP(&a, "a-test", "a", "default-a", "usage-a")
+	p.StringVarP(&b, "b-test", "b", "default-b", "usage-b")
+	p.RequiredTogether("a-test", "b-test")
+	err = p.ParseArgs([]string{"-a", "test", "-b", "test"})
+	testNoError(t, err)
+}
+
 func TestStringAllowOptionsFail(t *testing.T) {
 	p := NewArgParser("testprog")
 
@@ -101,6 +416,67 @@ func TestStringAllowOptionsFail(t *testing.T) {
 	args := []string{"-a", "test4"}
 	err := p.ParseArgs(args)
 	testError(t, err, "a-test: invalid value: \"test4\" is not among options: [\"test1\" \"test2\" \"test3\"]")
+
+	p = NewArgParser("testprog")
+	p.StringVarP(&a, "a-test", "a", "default-a", "usage-a")
+	p.StringAllowOptions(&a, "a-test", []string{"json", "text", "yaml"})
+	args = []string{"-a", "jsno"}
+	testError(
+		t, p.ParseArgs(args),
+		`a-test: invalid value: "jsno" is not among options: ["json" "text" "yaml"], `+
+			`did you mean "json"?`,
+	)
+}
+
+func TestStringAllowOptionsFoldFail(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "json", "usage-a")
+	p.StringAllowOptionsFold(&a, "a-test", []string{"json", "text"})
+	args := []string{"-a", "YAML"}
+	err := p.ParseArgs(args)
+	testError(t, err, "a-test: invalid value: \"YAML\" is not among options: [\"json\" \"text\"]")
+}
+
+func TestStringAllowOptionsFoldOK(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "json", "usage-a")
+	p.StringAllowOptionsFold(&a, "a-test", []string{"json", "text"})
+	args := []string{"-a", "JSON"}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
+	if a != "json" {
+		t.Fatalf("a: expected canonical value 'json', got: %q", a)
+	}
+}
+
+func TestStringAllowOptionsFuncFail(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	options := []string{"test1"}
+	p.StringVarP(&a, "a-test", "a", "default-a", "usage-a")
+	p.StringAllowOptionsFunc(&a, "a-test", func() []string { return options })
+	options = append(options, "test2")
+	args := []string{"-a", "test3"}
+	err := p.ParseArgs(args)
+	testError(t, err, "a-test: invalid value: \"test3\" is not among options: [\"test1\" \"test2\"]")
+}
+
+func TestStringAllowOptionsFuncOK(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	options := []string{"test1"}
+	p.StringVarP(&a, "a-test", "a", "default-a", "usage-a")
+	p.StringAllowOptionsFunc(&a, "a-test", func() []string { return options })
+	options = append(options, "test2")
+	args := []string{"-a", "test2"}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
 }
 
 func TestStringAllowOptionsOK(t *testing.T) {
@@ -136,6 +512,50 @@ func TestStringAllowRegexpOK(t *testing.T) {
 	testNoError(t, err)
 }
 
+func TestStringDenyOptionsFail(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "default-a", "usage-a")
+	p.StringDenyOptions(&a, "a-test", []string{"root", "admin"})
+	args := []string{"-a", "root"}
+	err := p.ParseArgs(args)
+	testError(t, err, "a-test: invalid value: \"root\" is a denied value")
+}
+
+func TestStringDenyOptionsOK(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "default-a", "usage-a")
+	p.StringDenyOptions(&a, "a-test", []string{"root", "admin"})
+	args := []string{"-a", "user"}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
+}
+
+func TestStringDenyRegexpFail(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringPosVar(&a, "a", "usage-a")
+	p.StringDenyRegexp(&a, "a", "^-")
+	args := []string{"--", "-x"}
+	err := p.ParseArgs(args)
+	testError(t, err, "a: invalid value: \"-x\" is matching denied regexp \"^-\"")
+}
+
+func TestStringDenyRegexpOK(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringPosVar(&a, "a", "usage-a")
+	p.StringDenyRegexp(&a, "a", "^-")
+	args := []string{"x"}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
+}
+
 func TestStringPosVarFail(t *testing.T) {
 	p := NewArgParser("testprog")
 
@@ -274,3 +694,99 @@ func TestStringPosNVarOKMin3Max3(t *testing.T) {
 		t.Fatalf("a[2]: expected parsed value 'c', got: %q", a[2])
 	}
 }
+
+func TestStringSliceAllowOptionsFail(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a []string
+	p.StringSliceVarP(&a, "a-test", "a", nil, "usage-a")
+	p.StringSliceAllowOptions(&a, "a-test", []string{"test1", "test2"})
+	args := []string{"-a", "test1,test3"}
+	err := p.ParseArgs(args)
+	testError(t, err, "a-test[1]: invalid value: \"test3\" is not among options: [\"test1\" \"test2\"]")
+}
+
+func TestStringSliceAllowOptionsOK(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a []string
+	p.StringArrayVarP(&a, "a-test", "a", nil, "usage-a")
+	p.StringSliceAllowOptions(&a, "a-test", []string{"test1", "test2"})
+	args := []string{"-a", "test2", "-a", "test1"}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
+}
+
+func TestStringSliceAllowRegexpFail(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a []string
+	p.StringPosNVar(&a, "a", "usage-a", 0, -1)
+	p.StringSliceAllowRegexp(&a, "a", "^a")
+	args := []string{"abc", "bcd"}
+	err := p.ParseArgs(args)
+	testError(t, err, "a[1]: invalid value: \"bcd\" is not matching regexp \"^a\"")
+}
+
+func TestStringSliceAllowRegexpOK(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a []string
+	p.StringPosNVar(&a, "a", "usage-a", 0, -1)
+	p.StringSliceAllowRegexp(&a, "a", "^a")
+	args := []string{"abc", "acd"}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
+}
+
+func TestTransformOK(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "default-a", "usage-a")
+	p.Transform(&a, "a-test", strings.TrimSpace)
+	p.Transform(&a, "a-test", strings.ToLower)
+	p.StringAllowOptions(&a, "a-test", []string{"test1", "test2"})
+	args := []string{"-a", " Test1 "}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
+	if a != "test1" {
+		t.Fatalf("a: expected transformed value 'test1', got: %q", a)
+	}
+}
+
+func TestValidateAfterFail(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a int
+	p.IntVarP(&a, "a-test", "a", 1, "usage-a")
+	var b int
+	p.IntVarP(&b, "b-test", "b", 2, "usage-b")
+	p.ValidateAfter(func(p *ArgParser) error {
+		if a > b {
+			return errors.New("a-test must not be greater than b-test")
+		}
+		return nil
+	})
+	args := []string{"-a", "3"}
+	err := p.ParseArgs(args)
+	testError(t, err, "a-test must not be greater than b-test")
+}
+
+func TestValidateAfterOK(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a int
+	p.IntVarP(&a, "a-test", "a", 1, "usage-a")
+	var b int
+	p.IntVarP(&b, "b-test", "b", 2, "usage-b")
+	p.ValidateAfter(func(p *ArgParser) error {
+		if a > b {
+			return errors.New("a-test must not be greater than b-test")
+		}
+		return nil
+	})
+	args := []string{"-a", "2"}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
+}
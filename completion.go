@@ -0,0 +1,566 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/pflag"
+)
+
+// completeArg is the hidden first argument making ParseArgs() write the
+// completion candidates of the last argument, given the arguments preceding
+// it, e.g. "prog __complete --format j". The candidates are written one per
+// line, optionally followed by a tab and a description, and then a directive
+// line, telling the shell what to complete if there are no candidates:
+// ":default" or ":files" for file names, ":files" followed by space-separated
+// patterns for file names matching any of them, ":dirs" for directories, or
+// ":nofiles" for nothing.
+const completeArg = "__complete"
+
+var completionFuncRegexp = regexp.MustCompile(`[^0-9A-Za-z_]`)
+
+// CompletionShells are the shells supported by GenCompletion().
+var CompletionShells = []string{"bash", "fish", "powershell", "zsh"}
+
+// ErrComplete is returned by ParseArgs() after writing completion candidates
+// for the completion scripts, when the exit function set using SetExit()
+// returns.
+var ErrComplete = errors.New("completion requested")
+
+// Candidate is a shell completion candidate, see Complete().
+type Candidate struct {
+	Value       string
+	Description string // displayed by shells supporting it
+}
+
+// Complete defines a function returning the completion candidates of a flag's
+// or positional argument's value, called with the value being completed when
+// the shell completes it. Candidates not prefixed by that value are omitted.
+// Positional arguments are completed in the order defined, counting the
+// arguments given that are not flags or flag values, and a varying positional
+// argument is completed until its maximum number of values is given.
+func (p *ArgParser) Complete(name string, fn func(toComplete string) []Candidate) {
+	p.checkCompleteTarget("complete", name)
+	if fn == nil {
+		p.die("complete: %s: cannot be defined with nil func", name)
+	}
+	if p.completions == nil {
+		p.completions = map[string]func(string) []Candidate{}
+	}
+	p.completions[name] = fn
+}
+
+// CompleteDirs defines that the given argument's value is completed as a
+// directory name, when there are no other completion candidates.
+func (p *ArgParser) CompleteDirs(name string) {
+	p.checkCompleteTarget("complete dirs", name)
+	p.setCompletionHint(name, ":dirs")
+}
+
+// CompleteFiles defines that the given argument's value is completed as a
+// file name, matching any of the given patterns, e.g. "*.yaml", if any, when
+// there are no other completion candidates. Directories are always completed,
+// to allow completing files in them.
+func (p *ArgParser) CompleteFiles(name string, patterns ...string) {
+	p.checkCompleteTarget("complete files", name)
+	for _, pattern := range patterns {
+		if pattern == "" || strings.ContainsFunc(pattern, unicode.IsSpace) {
+			p.die("complete files: %s: invalid pattern: %q", name, pattern)
+		}
+	}
+	p.setCompletionHint(name, strings.Join(append([]string{":files"}, patterns...), " "))
+}
+
+// CompleteNoFiles defines that the given argument's value is not completed as
+// a file name, when there are no other completion candidates.
+func (p *ArgParser) CompleteNoFiles(name string) {
+	p.checkCompleteTarget("complete no files", name)
+	p.setCompletionHint(name, ":nofiles")
+}
+
+// GenBashCompletion writes a bash completion script for the program, which
+// completes the flags, and values using the functions defined by Complete()
+// or the allowed options, see StringAllowOptions() and its variants. Other
+// values are completed as file names, unless defined otherwise using
+// CompleteDirs(), CompleteFiles() or CompleteNoFiles(). The candidates are
+// requested from the program at completion time.
+func (p *ArgParser) GenBashCompletion(w io.Writer) error {
+	var b strings.Builder
+	fn := p.completionFunc()
+	fmt.Fprintf(&b, "# bash completion for %s\n\n", p.Name)
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString(`    local line="${COMP_LINE:0:COMP_POINT}" cur="${COMP_WORDS[COMP_CWORD]}"
+    local -a words
+    read -ra words <<< "$line"
+    if [[ -z $line || $line == *[[:space:]] ]]; then
+        words+=("")
+    fi
+    local word="${words[${#words[@]}-1]}" value directive=:default
+    COMPREPLY=()
+    while IFS= read -r value; do
+        if [[ $value == :* ]]; then
+            directive="$value"
+        else
+            value="${value%%$'\t'*}"
+            COMPREPLY+=("${value#"${word%"$cur"}"}")
+        fi
+    done < <("${words[0]}" __complete "${words[@]:1}" 2>/dev/null)
+    if [[ ${#COMPREPLY[@]} -gt 0 || $directive == :nofiles ]]; then
+        return
+    fi
+    compopt -o filenames 2>/dev/null
+    case "$directive" in
+        :dirs)
+            COMPREPLY=($(compgen -d -- "$cur"))
+            ;;
+        ":files "*)
+            local pattern
+            local -a patterns
+            read -ra patterns <<< "${directive#:files }"
+            COMPREPLY=($(compgen -d -- "$cur"))
+            for pattern in "${patterns[@]}"; do
+                COMPREPLY+=($(compgen -f -X "!$pattern" -- "$cur"))
+            done
+            ;;
+        *)
+            COMPREPLY=($(compgen -f -- "$cur"))
+            ;;
+    esac
+}
+`)
+	fmt.Fprintf(&b, "\ncomplete -F %s %s\n", fn, shellQuote(p.Name))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// GenCompletion writes a completion script for the given shell, one of
+// CompletionShells, see GenBashCompletion().
+func (p *ArgParser) GenCompletion(w io.Writer, shell string) error {
+	switch shell {
+	case "bash":
+		return p.GenBashCompletion(w)
+	case "fish":
+		return p.GenFishCompletion(w)
+	case "powershell":
+		return p.GenPowerShellCompletion(w)
+	case "zsh":
+		return p.GenZshCompletion(w)
+	}
+	return fmt.Errorf("unsupported shell: %q", shell)
+}
+
+// GenFishCompletion writes a fish completion script for the program,
+// completing the same as GenBashCompletion(), and displaying the usage of the
+// flags and the descriptions of the candidates.
+func (p *ArgParser) GenFishCompletion(w io.Writer) error {
+	var b strings.Builder
+	fn := p.completionFunc()
+	fmt.Fprintf(&b, "# fish completion for %s\n\n", p.Name)
+	fmt.Fprintf(&b, "function %s\n", fn)
+	b.WriteString(`    set -l args (commandline -opc)
+    set -l current (commandline -ct)
+    set -l prog $args[1]
+    set -e args[1]
+    set -l directive :default
+    set -l found
+    for line in ($prog __complete $args "$current" 2>/dev/null)
+        switch $line
+            case ':*'
+                set directive $line
+            case '*'
+                set found 1
+                echo $line
+        end
+    end
+    if test -n "$found"
+        return
+    end
+    switch $directive
+        case :nofiles
+        case :dirs
+            __fish_complete_directories "$current"
+        case ':files *'
+            set -l patterns (string split ' ' (string replace ':files ' '' $directive))
+            for path in (__fish_complete_path "$current")
+                if string match -q -- '*/' $path
+                    echo $path
+                    continue
+                end
+                for pattern in $patterns
+                    if string match -q -- $pattern $path
+                        echo $path
+                        break
+                    end
+                end
+            end
+        case '*'
+            __fish_complete_path "$current"
+    end
+end
+`)
+	fmt.Fprintf(&b, "\ncomplete -c %s -f -a '(%s)'\n", fishQuote(p.Name), fn)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// GenPowerShellCompletion writes a PowerShell completion script for the
+// program, registering an argument completer completing the same as
+// GenBashCompletion(), and displaying the usage of the flags and the
+// descriptions of the candidates.
+func (p *ArgParser) GenPowerShellCompletion(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# powershell completion for %s\n\n", p.Name)
+	fmt.Fprintf(&b, "Register-ArgumentCompleter -Native -CommandName %s ", psQuote(p.Name))
+	b.WriteString(`-ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $words = @($commandAst.CommandElements |
+        Where-Object { $_.Extent.EndOffset -lt $cursorPosition } |
+        ForEach-Object { $_.ToString() })
+    $current = $wordToComplete
+    if ($current -eq '' -and $PSVersionTable.PSVersion -lt [version]'7.3') {
+        $current = '""'
+    }
+    $lines = @(& $words[0] __complete @($words | Select-Object -Skip 1) $current 2>$null)
+    $directive = ':default'
+    $results = @(foreach ($line in $lines) {
+        if ($line.StartsWith(':')) {
+            $directive = $line
+            continue
+        }
+        $value, $description = $line -split "` + "`t" + `", 2
+        if (-not $description) {
+            $description = $value
+        }
+        [System.Management.Automation.CompletionResult]::new(
+            $value, $value, 'ParameterValue', $description)
+    })
+    if ($results.Count -gt 0 -or $directive -eq ':default' -or $directive -eq ':files') {
+        return $results
+    }
+    if ($directive -eq ':nofiles') {
+        return ''
+    }
+    $patterns = if ($directive.StartsWith(':files ')) { $directive.Substring(7) -split ' ' }
+    $parent = Split-Path -Parent $wordToComplete
+    Get-ChildItem -Path "$wordToComplete*" -ErrorAction SilentlyContinue |
+        Where-Object {
+            $name = $_.Name
+            $_.PSIsContainer -or
+                ($patterns -and @($patterns | Where-Object { $name -like $_ }).Count -gt 0)
+        } |
+        ForEach-Object {
+            $path = if ($parent) { Join-Path $parent $_.Name } else { $_.Name }
+            [System.Management.Automation.CompletionResult]::new(
+                $path, $_.Name, 'ProviderItem', $path)
+        }
+}
+`)
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// GenZshCompletion writes a zsh completion script for the program, completing
+// the same as GenBashCompletion(), and displaying the usage of the flags and
+// the descriptions of the candidates.
+func (p *ArgParser) GenZshCompletion(w io.Writer) error {
+	var b strings.Builder
+	fn := p.completionFunc()
+	fmt.Fprintf(&b, "#compdef %s\n\n", p.Name)
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString(`    local -a lines candidates
+    local line value directive=:default
+    lines=("${(@f)$("${words[1]}" __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    for line in "${lines[@]}"; do
+        if [[ $line == :* ]]; then
+            directive=$line
+        elif [[ -n $line ]]; then
+            value=${${line%%$'\t'*}//:/\\:}
+            if [[ $line == *$'\t'* ]]; then
+                candidates+=("$value:${line#*$'\t'}")
+            else
+                candidates+=("$value")
+            fi
+        fi
+    done
+    if (( ${#candidates} )); then
+        _describe -t values value candidates
+        return
+    fi
+    [[ $PREFIX == --*=* ]] && compset -P '*='
+    case $directive in
+        (:nofiles)
+            ;;
+        (:dirs)
+            _files -/
+            ;;
+        (':files '*)
+            _files -g "(${(j:|:)${(s: :)directive#:files }})"
+            ;;
+        (*)
+            _files
+            ;;
+    esac
+}
+`)
+	fmt.Fprintf(&b, "\ncompdef %s %s\n", fn, shellQuote(p.Name))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// InstallCompletion writes a completion script for the given shell, one of
+// CompletionShells, to the conventional per-user completion directory of the
+// shell, and returns the path written:
+//
+//   - bash: $XDG_DATA_HOME/bash-completion/completions/<name>
+//   - fish: $XDG_CONFIG_HOME/fish/completions/<name>.fish
+//   - zsh: $XDG_DATA_HOME/zsh/site-functions/_<name>, which must be in $fpath
+//
+// $XDG_DATA_HOME defaults to ~/.local/share, and $XDG_CONFIG_HOME to ~/.config.
+// PowerShell has no such directory; its script is instead to be dot-sourced
+// from the $PROFILE script.
+func (p *ArgParser) InstallCompletion(shell string) (string, error) {
+	var path string
+	switch shell {
+	case "bash":
+		path = filepath.Join("bash-completion", "completions", p.Name)
+	case "fish":
+		path = filepath.Join("fish", "completions", p.Name+".fish")
+	case "zsh":
+		path = filepath.Join("zsh", "site-functions", "_"+p.Name)
+	case "powershell":
+		return "", fmt.Errorf("install completion: %s: no per-user completion directory", shell)
+	default:
+		return "", fmt.Errorf("install completion: unsupported shell: %q", shell)
+	}
+
+	env, dir := "XDG_DATA_HOME", filepath.Join(".local", "share")
+	if shell == "fish" {
+		env, dir = "XDG_CONFIG_HOME", ".config"
+	}
+	base := os.Getenv(env)
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("install completion: %w", err)
+		}
+		base = filepath.Join(home, dir)
+	}
+	path = filepath.Join(base, path)
+
+	var b strings.Builder
+	if err := p.GenCompletion(&b, shell); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("install completion: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		return "", fmt.Errorf("install completion: %w", err)
+	}
+	return path, nil
+}
+
+// complete writes the completion candidates of the last argument, see
+// completeArg.
+func (p *ArgParser) complete(args []string) {
+	toComplete := ""
+	if len(args) > 0 {
+		toComplete = args[len(args)-1]
+		args = args[:len(args)-1]
+	}
+
+	var valueFlag *pflag.Flag
+	dashdash := false
+	positionals := 0
+	for _, arg := range args {
+		switch {
+		case valueFlag != nil:
+			valueFlag = nil
+		case dashdash || arg == "-" || !strings.HasPrefix(arg, "-"):
+			positionals++
+		case arg == "--":
+			dashdash = true
+		default:
+			valueFlag = p.completionValueFlag(arg)
+		}
+	}
+
+	name := ""
+	prefix := ""
+	var candidates []Candidate
+	directive := ":nofiles"
+	switch {
+	case valueFlag != nil:
+		name = valueFlag.Name
+	case !dashdash && strings.HasPrefix(toComplete, "--") && strings.Contains(toComplete, "="):
+		name, toComplete, _ = strings.Cut(toComplete[2:], "=")
+		prefix = "--" + name + "="
+	case !dashdash && strings.HasPrefix(toComplete, "-"):
+		candidates = p.completionFlags()
+	case positionals < len(p.pos):
+		name = p.pos[positionals].name
+	case p.posN != nil && (p.posN.maxN == -1 || positionals-len(p.pos) < p.posN.maxN):
+		name = p.posN.name
+	}
+	if name != "" {
+		candidates, directive = p.completionValues(name, toComplete)
+		if hint, ok := p.completionHints[name]; ok {
+			directive = hint
+		}
+	}
+
+	var b strings.Builder
+	for _, c := range candidates {
+		if !strings.HasPrefix(c.Value, toComplete) {
+			continue
+		}
+		b.WriteString(prefix + c.Value)
+		if c.Description != "" {
+			b.WriteString("\t" + strings.ReplaceAll(c.Description, "\n", " "))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(directive + "\n")
+	fmt.Fprint(p.out(), b.String())
+}
+
+// checkCompleteTarget verifies that name refers to a flag or positional
+// argument, which may still get completion definitions.
+func (p *ArgParser) checkCompleteTarget(prefix, name string) {
+	if p.Lookup(name) == nil && !p.isPositional(name) {
+		p.die("%s: undefined flag or positional argument: %s", prefix, name)
+	}
+	if p.Parsed() {
+		p.die("%s: %s: cannot define post-parse", prefix, name)
+	}
+}
+
+// completionFlags returns the completion candidates of the flag names,
+// omitting hidden and deprecated flags.
+func (p *ArgParser) completionFlags() []Candidate {
+	var candidates []Candidate
+	p.VisitAll(func(flag *pflag.Flag) {
+		if flag.Hidden || flag.Deprecated != "" {
+			return
+		}
+		candidates = append(candidates, Candidate{"--" + flag.Name, flag.Usage})
+		if flag.Shorthand != "" && flag.ShorthandDeprecated == "" {
+			candidates = append(candidates, Candidate{"-" + flag.Shorthand, flag.Usage})
+		}
+	})
+	return candidates
+}
+
+// completionFunc returns the name of the shell function of the completion
+// scripts.
+func (p *ArgParser) completionFunc() string {
+	return "_" + completionFuncRegexp.ReplaceAllString(p.Name, "_") + "_completion"
+}
+
+// completionValueFlag returns the flag given by arg if the following argument
+// is its value, otherwise nil.
+func (p *ArgParser) completionValueFlag(arg string) *pflag.Flag {
+	if name, ok := strings.CutPrefix(arg, "--"); ok {
+		if flag := p.Lookup(name); flag != nil && flag.NoOptDefVal == "" {
+			return flag
+		}
+		return nil
+	}
+	for i := 1; i < len(arg); i++ {
+		flag := p.ShorthandLookup(arg[i : i+1])
+		if flag == nil {
+			return nil
+		}
+		if flag.NoOptDefVal == "" {
+			if i == len(arg)-1 {
+				return flag
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// completionValues returns the completion candidates of the given argument's
+// value, and the directive for the shell. Unless defined using Complete(), the
+// candidates are the allowed options of the argument, see StringAllowOptions()
+// and StringSliceAllowOptions().
+func (p *ArgParser) completionValues(name, toComplete string) ([]Candidate, string) {
+	if fn, ok := p.completions[name]; ok {
+		return fn(toComplete), ":nofiles"
+	}
+	var candidates []Candidate
+	for _, a := range p.allowedOptions {
+		if a.name != name {
+			continue
+		}
+		options := a.options
+		if a.optionsFunc != nil {
+			options = a.optionsFunc()
+		}
+		for _, option := range options {
+			candidates = append(candidates, Candidate{Value: option})
+		}
+	}
+	head := ""
+	if flag := p.Lookup(name); flag != nil && flag.Value.Type() == "stringSlice" {
+		if i := strings.LastIndex(toComplete, ","); i != -1 {
+			head = toComplete[:i+1]
+		}
+	}
+	for _, a := range p.allowedSliceOpts {
+		if a.name == name {
+			for _, option := range a.options {
+				candidates = append(candidates, Candidate{Value: head + option})
+			}
+		}
+	}
+	if candidates == nil {
+		return nil, ":default"
+	}
+	return candidates, ":nofiles"
+}
+
+// isPositional returns whether name is the name of a positional argument.
+func (p *ArgParser) isPositional(name string) bool {
+	for _, pos := range p.pos {
+		if pos.name == name {
+			return true
+		}
+	}
+	return p.posN != nil && p.posN.name == name
+}
+
+// setCompletionHint sets the directive for the shell when there are no
+// completion candidates of the given argument's value.
+func (p *ArgParser) setCompletionHint(name, directive string) {
+	if p.completionHints == nil {
+		p.completionHints = map[string]string{}
+	}
+	p.completionHints[name] = directive
+}
+
+// fishQuote quotes s for fish.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+// psQuote quotes s for PowerShell.
+func psQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// shellQuote quotes s for POSIX shells.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
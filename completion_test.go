@@ -0,0 +1,361 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testComplete(t *testing.T, p *ArgParser, args []string, expected string) {
+	t.Helper()
+	code := -1
+	p.SetExit(func(c int) { code = c })
+	var out strings.Builder
+	p.SetOutput(&out)
+	if err := p.ParseArgs(append([]string{completeArg}, args...)); !errors.Is(err, ErrComplete) {
+		t.Fatalf("expected ErrComplete, got: %v", err)
+	}
+	if code != 0 || out.String() != expected {
+		t.Fatalf("%q: unexpected exit code %d and candidates:\n%s", args, code, out.String())
+	}
+}
+
+func testCompletionScript(t *testing.T, script, prefix, suffix string) {
+	t.Helper()
+	if !strings.HasPrefix(script, prefix) || !strings.HasSuffix(script, suffix) {
+		t.Fatalf("expected script starting with:\n%s\nending with:\n%s\ngot:\n%s",
+			prefix, suffix, script)
+	}
+}
+
+func TestCompleteDirs(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVar(&a, "a-test", "", "usage-a")
+	p.CompleteDirs("a-test")
+	var b string
+	p.StringVar(&b, "b-test", "", "usage-b")
+	p.StringAllowOptions(&b, "b-test", []string{"x", "y"})
+	p.CompleteDirs("b-test")
+
+	testComplete(t, p, []string{"--a-test", ""}, ":dirs\n")
+	testComplete(t, p, []string{"--b-test", ""}, "x\ny\n:dirs\n")
+}
+
+func TestCompleteFail(t *testing.T) {
+	p := NewArgParser("testprog")
+	fn := func(string) []Candidate { return nil }
+
+	testError(
+		t, p.Define(func() { p.Complete("a-test", fn) }),
+		"testprog: complete: undefined flag or positional argument: a-test",
+	)
+
+	var a string
+	p.StringVar(&a, "a-test", "", "usage-a")
+	testError(
+		t, p.Define(func() { p.Complete("a-test", nil) }),
+		"testprog: complete: a-test: cannot be defined with nil func",
+	)
+}
+
+func TestCompleteFilesFail(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	testError(
+		t, p.Define(func() { p.CompleteFiles("a-test") }),
+		"testprog: complete files: undefined flag or positional argument: a-test",
+	)
+
+	var a string
+	p.StringVar(&a, "a-test", "", "usage-a")
+	testError(
+		t, p.Define(func() { p.CompleteFiles("a-test", "*.yaml", "my *.yml") }),
+		`testprog: complete files: a-test: invalid pattern: "my *.yml"`,
+	)
+	testError(
+		t, p.Define(func() { p.CompleteFiles("a-test", "") }),
+		`testprog: complete files: a-test: invalid pattern: ""`,
+	)
+}
+
+func TestCompleteFilesOK(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVar(&a, "a-test", "", "usage-a")
+	p.CompleteFiles("a-test", "*.yaml", "*.yml")
+	var b string
+	p.StringVar(&b, "b-test", "", "usage-b")
+	p.CompleteFiles("b-test")
+	var pos1 string
+	p.StringPosVar(&pos1, "pos1", "usage-pos1")
+	p.Complete("pos1", func(string) []Candidate { return []Candidate{{"-", "stdin"}} })
+	p.CompleteFiles("pos1", "*.json")
+
+	testComplete(t, p, []string{"--a-test", ""}, ":files *.yaml *.yml\n")
+	testComplete(t, p, []string{"--a-test=x"}, ":files *.yaml *.yml\n")
+	testComplete(t, p, []string{"--b-test", ""}, ":files\n")
+	testComplete(t, p, []string{""}, "-\tstdin\n:files *.json\n")
+}
+
+func TestCompleteNoFiles(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVar(&a, "a-test", "", "usage-a")
+	p.CompleteNoFiles("a-test")
+	var b []string
+	p.StringPosNVar(&b, "b-test", "usage-b", 0, -1)
+	p.CompleteNoFiles("b-test")
+
+	testComplete(t, p, []string{"--a-test", ""}, ":nofiles\n")
+	testComplete(t, p, []string{""}, ":nofiles\n")
+}
+
+func TestCompleteOK(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "", "usage-a")
+	p.Complete("a-test", func(toComplete string) []Candidate {
+		return []Candidate{{"x1", "desc x1"}, {"x2", ""}, {"y" + toComplete, "desc\ny"}}
+	})
+	var b string
+	p.StringVar(&b, "b-test", "", "usage-b")
+	p.StringAllowOptions(&b, "b-test", []string{"json", "yaml"})
+	var c bool
+	p.BoolVarP(&c, "c-test", "c", false, "usage-c")
+	var d string
+	p.StringVar(&d, "d-test", "", "usage-d")
+	var e bool
+	p.BoolVar(&e, "e-test", false, "usage-e")
+	p.MarkHidden("e-test")
+	var pos1 string
+	p.StringPosVar(&pos1, "pos1", "usage-pos1")
+	p.Complete("pos1", func(string) []Candidate { return []Candidate{{"p1", ""}} })
+	var pos2 []string
+	p.StringPosNVar(&pos2, "pos2", "usage-pos2", 0, -1)
+
+	testComplete(t, p, []string{"--a-test", ""}, "x1\tdesc x1\nx2\ny\tdesc y\n:nofiles\n")
+	testComplete(t, p, []string{"-ca", "x"}, "x1\tdesc x1\nx2\n:nofiles\n")
+	testComplete(t, p, []string{"--a-test=y"}, "--a-test=yy\tdesc y\n:nofiles\n")
+	testComplete(t, p, []string{"--b-test", "j"}, "json\n:nofiles\n")
+	testComplete(t, p, []string{"--b-test=y"}, "--b-test=yaml\n:nofiles\n")
+	testComplete(t, p, []string{"--d-test", ""}, ":default\n")
+	testComplete(t, p, []string{"--b"}, "--b-test\tusage-b\n:nofiles\n")
+	testComplete(
+		t, p, []string{"-"},
+		"--help\tdisplay this help text and exit\n-h\tdisplay this help text and exit\n"+
+			"--a-test\tusage-a\n-a\tusage-a\n--b-test\tusage-b\n"+
+			"--c-test\tusage-c\n-c\tusage-c\n--d-test\tusage-d\n:nofiles\n",
+	)
+	testComplete(t, p, []string{""}, "p1\n:nofiles\n")
+	testComplete(t, p, []string{"-c", "--d-test", "x", ""}, "p1\n:nofiles\n")
+	testComplete(t, p, []string{"x", ""}, ":default\n")
+	testComplete(t, p, []string{"--", "-"}, ":nofiles\n")
+	testComplete(t, p, []string{}, "p1\n:nofiles\n")
+
+	p = NewArgParser("testprog")
+	testComplete(t, p, []string{""}, ":nofiles\n")
+}
+
+func TestCompleteOptions(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVar(&a, "a-test", "", "usage-a")
+	p.StringAllowOptionsFold(&a, "a-test", []string{"json", "yaml"})
+	var b string
+	p.StringVar(&b, "b-test", "", "usage-b")
+	calls := 0
+	p.StringAllowOptionsFunc(&b, "b-test", func() []string {
+		calls++
+		return []string{"x", "y"}
+	})
+	var c []string
+	p.StringSliceVar(&c, "c-test", nil, "usage-c")
+	p.StringSliceAllowOptions(&c, "c-test", []string{"x1", "x2", "y"})
+	var d []string
+	p.StringArrayVar(&d, "d-test", nil, "usage-d")
+	p.StringSliceAllowOptions(&d, "d-test", []string{"x,y", "z"})
+	var pos1 string
+	p.StringPosVar(&pos1, "pos1", "usage-pos1")
+	p.StringAllowOptions(&pos1, "pos1", []string{"start", "stop"})
+	var pos2 []string
+	p.StringPosNVar(&pos2, "pos2", "usage-pos2", 0, -1)
+	p.StringSliceAllowOptions(&pos2, "pos2", []string{"x", "y"})
+
+	testComplete(t, p, []string{"--a-test", ""}, "json\nyaml\n:nofiles\n")
+	if calls != 0 {
+		t.Fatalf("expected no calls to the options func, got %d", calls)
+	}
+	testComplete(t, p, []string{"--b-test", ""}, "x\ny\n:nofiles\n")
+	if calls != 1 {
+		t.Fatalf("expected 1 call to the options func, got %d", calls)
+	}
+	testComplete(t, p, []string{"--c-test", "x"}, "x1\nx2\n:nofiles\n")
+	testComplete(
+		t, p, []string{"--c-test=x1,"},
+		"--c-test=x1,x1\n--c-test=x1,x2\n--c-test=x1,y\n:nofiles\n",
+	)
+	testComplete(t, p, []string{"--d-test", "x,"}, "x,y\n:nofiles\n")
+	testComplete(t, p, []string{"st"}, "start\nstop\n:nofiles\n")
+	testComplete(t, p, []string{"start", "x", ""}, "x\ny\n:nofiles\n")
+}
+
+func TestCompletePositionals(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "", "usage-a")
+	var b bool
+	p.BoolVarP(&b, "b-test", "b", false, "usage-b")
+	var pos1 string
+	p.StringPosVar(&pos1, "pos1", "usage-pos1")
+	p.StringAllowOptions(&pos1, "pos1", []string{"start", "stop"})
+	var pos2 string
+	p.StringPosVar(&pos2, "pos2", "usage-pos2")
+	p.CompleteDirs("pos2")
+	var pos3 []string
+	p.StringPosNVar(&pos3, "pos3", "usage-pos3", 1, 2)
+	p.Complete("pos3", func(string) []Candidate { return []Candidate{{"x", ""}} })
+
+	testComplete(t, p, []string{"s"}, "start\nstop\n:nofiles\n")
+	testComplete(t, p, []string{"-b", "--a-test", "x", "st"}, "start\nstop\n:nofiles\n")
+	testComplete(t, p, []string{"start", "-a", "x", ""}, ":dirs\n")
+	testComplete(t, p, []string{"-", "-b", ""}, ":dirs\n")
+	testComplete(t, p, []string{"start", "dir", ""}, "x\n:nofiles\n")
+	testComplete(t, p, []string{"start", "dir", "x", "-b", ""}, "x\n:nofiles\n")
+	testComplete(t, p, []string{"start", "dir", "x", "x", ""}, ":nofiles\n")
+	testComplete(t, p, []string{"--", "-b", "dir", ""}, "x\n:nofiles\n")
+}
+
+func TestGenBashCompletion(t *testing.T) {
+	p := NewArgParser("test-prog")
+	var out strings.Builder
+	testNoError(t, p.GenBashCompletion(&out))
+	testCompletionScript(
+		t, out.String(),
+		"# bash completion for test-prog\n\n_test_prog_completion() {\n",
+		"}\n\ncomplete -F _test_prog_completion 'test-prog'\n",
+	)
+}
+
+func TestGenCompletion(t *testing.T) {
+	p := NewArgParser("test-prog")
+	for _, shell := range CompletionShells {
+		var out, expected strings.Builder
+		testNoError(t, p.GenCompletion(&out, shell))
+		switch shell {
+		case "bash":
+			testNoError(t, p.GenBashCompletion(&expected))
+		case "fish":
+			testNoError(t, p.GenFishCompletion(&expected))
+		case "powershell":
+			testNoError(t, p.GenPowerShellCompletion(&expected))
+		case "zsh":
+			testNoError(t, p.GenZshCompletion(&expected))
+		}
+		if out.String() != expected.String() {
+			t.Fatalf("%s: unexpected script:\n%s", shell, out.String())
+		}
+	}
+	testError(t, p.GenCompletion(io.Discard, "tcsh"), `unsupported shell: "tcsh"`)
+}
+
+func TestGenFishCompletion(t *testing.T) {
+	p := NewArgParser("test-prog")
+	var out strings.Builder
+	testNoError(t, p.GenFishCompletion(&out))
+	testCompletionScript(
+		t, out.String(),
+		"# fish completion for test-prog\n\nfunction _test_prog_completion\n",
+		"end\n\ncomplete -c 'test-prog' -f -a '(_test_prog_completion)'\n",
+	)
+}
+
+func TestGenPowerShellCompletion(t *testing.T) {
+	p := NewArgParser("test-prog's")
+	var out strings.Builder
+	testNoError(t, p.GenPowerShellCompletion(&out))
+	testCompletionScript(
+		t, out.String(),
+		"# powershell completion for test-prog's\n\n"+
+			"Register-ArgumentCompleter -Native -CommandName 'test-prog''s' -ScriptBlock {\n",
+		"                $path, $_.Name, 'ProviderItem', $path)\n        }\n}\n",
+	)
+}
+
+func TestGenZshCompletion(t *testing.T) {
+	p := NewArgParser("test-prog")
+	var out strings.Builder
+	testNoError(t, p.GenZshCompletion(&out))
+	testCompletionScript(
+		t, out.String(),
+		"#compdef test-prog\n\n_test_prog_completion() {\n",
+		"}\n\ncompdef _test_prog_completion 'test-prog'\n",
+	)
+}
+
+func TestInstallCompletionFail(t *testing.T) {
+	p := NewArgParser("test-prog")
+
+	_, err := p.InstallCompletion("tcsh")
+	testError(t, err, `install completion: unsupported shell: "tcsh"`)
+	_, err = p.InstallCompletion("powershell")
+	testError(t, err, "install completion: powershell: no per-user completion directory")
+
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XDG_DATA_HOME", file)
+	_, err = p.InstallCompletion("bash")
+	if err == nil || !strings.HasPrefix(err.Error(), "install completion: mkdir ") {
+		t.Fatalf("expected mkdir error, got: %v", err)
+	}
+}
+
+func TestInstallCompletionOK(t *testing.T) {
+	p := NewArgParser("test-prog")
+	data := t.TempDir()
+	config := t.TempDir()
+	home := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", data)
+	t.Setenv("XDG_CONFIG_HOME", config)
+	t.Setenv("HOME", home)
+
+	for shell, expected := range map[string]string{
+		"bash": filepath.Join(data, "bash-completion", "completions", "test-prog"),
+		"fish": filepath.Join(config, "fish", "completions", "test-prog.fish"),
+		"zsh":  filepath.Join(data, "zsh", "site-functions", "_test-prog"),
+	} {
+		path, err := p.InstallCompletion(shell)
+		testNoError(t, err)
+		if path != expected {
+			t.Fatalf("%s: expected path %q, got %q", shell, expected, path)
+		}
+		var script strings.Builder
+		testNoError(t, p.GenCompletion(&script, shell))
+		if b, err := os.ReadFile(path); err != nil || string(b) != script.String() {
+			t.Fatalf("%s: unexpected script written: %v\n%s", shell, err, b)
+		}
+	}
+
+	t.Setenv("XDG_DATA_HOME", "")
+	path, err := p.InstallCompletion("bash")
+	testNoError(t, err)
+	expected := filepath.Join(home, ".local", "share", "bash-completion", "completions", "test-prog")
+	if path != expected {
+		t.Fatalf("expected path %q, got %q", expected, path)
+	}
+}
@@ -0,0 +1,138 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"slices"
+
+	"github.com/spf13/pflag"
+)
+
+// ConfigFormat is the format of a config file, see ConfigFile().
+type ConfigFormat int
+
+const (
+	// ConfigYAML is a subset of YAML: nested block mappings of scalars, and
+	// sequences of scalars, in block or flow style. Nested keys are joined
+	// using ".", e.g. "level" under "log" as "log.level". Only a single
+	// document is supported, optionally started with "---".
+	ConfigYAML ConfigFormat = iota
+	// ConfigTOML is a subset of TOML: tables, and keys with single-line
+	// values, or arrays of them. Table and dotted keys are joined using ".",
+	// e.g. "level" in table "log" as "log.level". Values other than strings
+	// are used as written, without underscores in numbers. Multi-line
+	// strings, inline tables and arrays of tables are not supported, and
+	// values that are not valid TOML are rejected.
+	ConfigTOML
+	// ConfigINI is the INI format, with "key = value" or "key: value" entries
+	// and full-line comments starting with ";" or "#". Section and key are
+	// joined using ".", e.g. "level" in section "log" as "log.level", and
+	// subsections, e.g. [log "file"], as "log.file.level". Values may be
+	// enclosed in quotes, and repeated keys give multiple values.
+	ConfigINI
+)
+
+type configFile struct {
+	path   string
+	format ConfigFormat
+}
+
+// ConfigFile adds a config file supplying values for the flags not given by a
+// source of higher precedence, see SetPrecedence(), keyed by flag name unless
+// mapped to other keys using ConfigKey(). The file is read by ParseArgs(),
+// before any checks such as required flags are enforced, and is ignored if it
+// does not exist. Files added later take precedence. A flag set from a config
+// file counts as given by all checks, as if given on the command line, e.g. it
+// conflicts with the other flags of MutuallyExclusive().
+func (p *ArgParser) ConfigFile(path string, format ConfigFormat) {
+	if path == "" {
+		p.die("config file: cannot be defined with empty path")
+	}
+	if p.Parsed() {
+		p.die("config file: %s: cannot define post-parse", path)
+	}
+	p.configFiles = append(p.configFiles, configFile{path, format})
+}
+
+// ConfigKey sets the key of the given flag in config files, e.g.
+// "log.level" for the flag log-level.
+func (p *ArgParser) ConfigKey(name, key string) {
+	if flag := p.Lookup(name); flag == nil {
+		p.die("config key: undefined flag: %s", name)
+	}
+	if key == "" {
+		p.die("config key: %s: cannot be defined with empty key", name)
+	}
+	if p.configKeys == nil {
+		p.configKeys = map[string]string{}
+	}
+	p.configKeys[name] = key
+}
+
+// configKey returns the key of the given flag in config files.
+func (p *ArgParser) configKey(name string) string {
+	if key, ok := p.configKeys[name]; ok {
+		return key
+	}
+	return name
+}
+
+// parseConfig sets the flags not set by a source of higher precedence from
+// the config files.
+func (p *ArgParser) parseConfig() error {
+	flags := map[string]*pflag.Flag{}
+	p.VisitAll(func(flag *pflag.Flag) {
+		if flag.Name != "help" && flag.Name != "help-advanced" {
+			flags[p.configKey(flag.Name)] = flag
+		}
+	})
+	for i := len(p.configFiles) - 1; i >= 0; i-- {
+		file := p.configFiles[i]
+		data, err := os.ReadFile(file.path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return p.errorf("error.config", file.path, err)
+		}
+		values, err := parseConfigData(data, file.format)
+		if err != nil {
+			return p.errorf("error.config", file.path, err)
+		}
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			flag, ok := flags[key]
+			if !ok {
+				return p.errorf("error.config-key", file.path, key)
+			}
+			if _, ok := p.valueSources[flag.Name]; ok {
+				continue
+			}
+			if err := p.setValues(flag, SourceConfig, values[key]); err != nil {
+				return p.errorf("error.config", file.path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// parseConfigData parses a config file into values keyed by config key.
+func parseConfigData(data []byte, format ConfigFormat) (map[string][]string, error) {
+	switch format {
+	case ConfigYAML:
+		return parseYAML(data)
+	case ConfigINI:
+		return parseINI(data)
+	case ConfigTOML:
+		return parseTOML(data)
+	}
+	return nil, errors.New("unknown config format")
+}
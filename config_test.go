@@ -0,0 +1,106 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return path
+}
+
+func TestConfigFileFail(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a int
+	p.IntVar(&a, "a-test", 0, "usage-a")
+	path := writeConfig(t, "config.yaml", "a-test: x\n")
+	p.ConfigFile(path, ConfigYAML)
+	testError(
+		t, p.ParseArgs([]string{}),
+		"config file "+path+`: invalid argument "x" for "--a-test" flag: `+
+			`strconv.ParseInt: parsing "x": invalid syntax`,
+	)
+
+	p = NewArgParser("testprog")
+	p.IntVar(&a, "a-test", 0, "usage-a")
+	path = writeConfig(t, "config.yaml", "a-test: 1\nb-test: 2\n")
+	p.ConfigFile(path, ConfigYAML)
+	testError(t, p.ParseArgs([]string{}), "config file "+path+": unknown key: b-test")
+
+	p = NewArgParser("testprog")
+	p.IntVar(&a, "a-test", 0, "usage-a")
+	p.IntAllowRange(&a, "a-test", 1, 9)
+	path = writeConfig(t, "config.yaml", "a-test: 10\n")
+	p.ConfigFile(path, ConfigYAML)
+	testError(t, p.ParseArgs([]string{}), "a-test: invalid value: 10 is not within range 1 to 9")
+
+	p = NewArgParser("testprog")
+	p.IntVar(&a, "a-test", 0, "usage-a")
+	var b int
+	p.IntVar(&b, "b-test", 0, "usage-b")
+	p.MutuallyExclusive("a-test", "b-test")
+	path = writeConfig(t, "config.yaml", "a-test: 1\n")
+	p.ConfigFile(path, ConfigYAML)
+	args := []string{"--b-test", "2"}
+	testError(t, p.ParseArgs(args), "a-test and b-test are mutually exclusive flags")
+}
+
+func TestConfigFileOK(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVar(&a, "a-test", "", "usage-a")
+	p.Required("a-test")
+	var b string
+	p.StringVar(&b, "b-test", "", "usage-b")
+	p.ConfigKey("b-test", "b.test")
+	var c []string
+	p.StringSliceVar(&c, "c-test", nil, "usage-c")
+	var d string
+	p.StringVar(&d, "d-test", "", "usage-d")
+	var e string
+	p.StringVar(&e, "e-test", "", "usage-e")
+	p.BindEnv("e-test", "TESTPROG_E")
+	t.Setenv("TESTPROG_E", "env-e")
+	p.ConfigFile(filepath.Join(t.TempDir(), "nonexistent.yaml"), ConfigYAML)
+	p.ConfigFile(writeConfig(t, "1.yaml", "a-test: config-a1\nd-test: config-d\n"), ConfigYAML)
+	p.ConfigFile(writeConfig(t, "2.yaml", `
+a-test: config-a2
+b:
+  test: config-b
+c-test:
+  - x,y
+  - z
+d-test: ~
+e-test: config-e
+`), ConfigYAML)
+	testNoError(t, p.ParseArgs([]string{"--d-test=arg-d"}))
+	if a != "config-a2" || b != "config-b" || d != "arg-d" || e != "env-e" {
+		t.Fatalf("unexpected values: %q, %q, %q, %q", a, b, d, e)
+	}
+	if !slices.Equal(c, []string{"x,y", "z"}) {
+		t.Fatalf("unexpected values: %q", c)
+	}
+
+	p = NewArgParser("testprog")
+	p.StringVar(&a, "a-test", "", "usage-a")
+	p.StringVar(&b, "b-test", "", "usage-b")
+	p.ConfigKey("b-test", "b.test")
+	path := writeConfig(t, "config.toml", "a-test = \"config-a\"\n[b]\ntest = 'config-b'\n")
+	p.ConfigFile(path, ConfigTOML)
+	testNoError(t, p.ParseArgs([]string{}))
+	if a != "config-a" || b != "config-b" {
+		t.Fatalf("unexpected values: %q, %q", a, b)
+	}
+}
@@ -0,0 +1,132 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var dotEnvNameRegexp = regexp.MustCompile(`^[A-Za-z_][0-9A-Za-z_.]*$`)
+
+// DotEnvFile adds a dotenv (.env) file of environment variables, used for the
+// variables bound to flags, see BindEnv(), that are not set in the
+// environment. The file is read by ParseArgs(), and is ignored if it does not
+// exist. Files added later take precedence.
+//
+// Each line is an assignment, e.g. NAME=value, optionally preceded by
+// "export". Values may be enclosed in single quotes, taken literally, or in
+// double quotes, where escape sequences such as \n are interpreted. Lines
+// starting with "#" and the rest of a line following " #" after a value are
+// comments, while any other text following a quoted value is rejected.
+func (p *ArgParser) DotEnvFile(path string) {
+	if path == "" {
+		p.die("dotenv file: cannot be defined with empty path")
+	}
+	if p.Parsed() {
+		p.die("dotenv file: %s: cannot define post-parse", path)
+	}
+	p.dotEnvFiles = append(p.dotEnvFiles, path)
+}
+
+// getenv returns the value of the given environment variable, from the
+// environment or otherwise from the dotenv files.
+func (p *ArgParser) getenv(name string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return p.dotEnv[name]
+}
+
+// parseDotEnvFiles reads the dotenv files.
+func (p *ArgParser) parseDotEnvFiles() error {
+	p.dotEnv = map[string]string{}
+	for _, path := range p.dotEnvFiles {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return p.errorf("error.dotenv", path, err)
+		}
+		if err := parseDotEnv(data, p.dotEnv); err != nil {
+			return p.errorf("error.dotenv", path, err)
+		}
+	}
+	return nil
+}
+
+// parseDotEnv parses the assignments of a dotenv file into env.
+func parseDotEnv(data []byte, env map[string]string) error {
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		name, v, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !dotEnvNameRegexp.MatchString(name) {
+			return fmt.Errorf("line %d: expected assignment of variable, e.g. NAME=value", i+1)
+		}
+		v = strings.TrimSpace(v)
+		switch {
+		case strings.HasPrefix(v, `"`):
+			end := dotEnvQuoteEnd(v)
+			if end == -1 {
+				return fmt.Errorf("line %d: invalid double-quoted value: %s", i+1, v)
+			}
+			uq, err := strconv.Unquote(v[:end+1])
+			if err != nil {
+				return fmt.Errorf("line %d: invalid double-quoted value: %s", i+1, v)
+			}
+			if !dotEnvComment(v[end+1:]) {
+				return fmt.Errorf("line %d: unexpected text after quoted value: %s", i+1, v)
+			}
+			v = uq
+		case strings.HasPrefix(v, "'"):
+			end := dotEnvQuoteEnd(v)
+			if end == -1 {
+				return fmt.Errorf("line %d: invalid single-quoted value: %s", i+1, v)
+			}
+			if !dotEnvComment(v[end+1:]) {
+				return fmt.Errorf("line %d: unexpected text after quoted value: %s", i+1, v)
+			}
+			v = v[1:end]
+		default:
+			if j := strings.Index(v, " #"); j != -1 {
+				v = strings.TrimSpace(v[:j])
+			}
+		}
+		env[name] = v
+	}
+	return nil
+}
+
+// dotEnvComment returns whether s, following a quoted value, is empty or a
+// comment preceded by whitespace.
+func dotEnvComment(s string) bool {
+	t := strings.TrimLeft(s, " \t")
+	return s == "" || (t != s && (t == "" || t[0] == '#'))
+}
+
+// dotEnvQuoteEnd returns the index of the quote closing the quoted value v, or
+// -1 if it is unterminated. In double quotes, a backslash escapes the
+// following character.
+func dotEnvQuoteEnd(v string) int {
+	for i := 1; i < len(v); i++ {
+		switch {
+		case v[0] == '"' && v[i] == '\\':
+			i++
+		case v[i] == v[0]:
+			return i
+		}
+	}
+	return -1
+}
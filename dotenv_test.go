@@ -0,0 +1,78 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"maps"
+	"path/filepath"
+	"testing"
+)
+
+func TestDotEnvFile(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVar(&a, "a-test", "", "usage-a")
+	p.BindEnv("a-test", "TESTPROG_A")
+	var b string
+	p.StringVar(&b, "b-test", "", "usage-b")
+	p.BindEnv("b-test", "TESTPROG_B")
+	t.Setenv("TESTPROG_A", "env-a")
+	p.DotEnvFile(filepath.Join(t.TempDir(), "nonexistent.env"))
+	p.DotEnvFile(writeConfig(t, ".env", "TESTPROG_A=dotenv-a\nTESTPROG_B=dotenv-b\n"))
+	testNoError(t, p.ParseArgs([]string{}))
+	if a != "env-a" || b != "dotenv-b" {
+		t.Fatalf("unexpected values: %q, %q", a, b)
+	}
+}
+
+func TestParseDotEnvFail(t *testing.T) {
+	for _, data := range []string{
+		"A",
+		"1A=x",
+		"A B=x",
+		`A="x`,
+		"A='x",
+		`A="x" y`,
+		`A="x"y`,
+		`A="x"#y`,
+		"A='x' y",
+		"A='x''y'",
+	} {
+		if err := parseDotEnv([]byte(data), map[string]string{}); err == nil {
+			t.Fatalf("expected error for %q", data)
+		}
+	}
+	testError(
+		t, parseDotEnv([]byte("A=1\nB=\"a\" b\n"), map[string]string{}),
+		`line 2: unexpected text after quoted value: "a" b`,
+	)
+}
+
+func TestParseDotEnvOK(t *testing.T) {
+	data := `# comment
+A=1
+export B = x y # comment
+C="x\ny" # comment
+D='x\ny'
+E=
+F='x' # it's "y"
+G="x\"y"
+`
+	env := map[string]string{}
+	testNoError(t, parseDotEnv([]byte(data), env))
+	expected := map[string]string{
+		"A": "1",
+		"B": "x y",
+		"C": "x\ny",
+		"D": `x\ny`,
+		"E": "",
+		"F": "x",
+		"G": `x"y`,
+	}
+	if !maps.Equal(env, expected) {
+		t.Fatalf("expected %q, got %q", expected, env)
+	}
+}
@@ -0,0 +1,183 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// AutoEnv binds all flags to environment variables named after the flags,
+// prefixed with prefix, e.g. MYAPP_SOME_FLAG for the flag some-flag with the
+// prefix "MYAPP". The variables are applied like those of BindEnv(), after
+// any bound explicitly. Flags can be excluded using NoAutoEnv(). The prefix
+// cannot be empty, as unprefixed names such as PATH and HOME would be bound;
+// use BindEnv() for such names.
+func (p *ArgParser) AutoEnv(prefix string) {
+	if prefix == "" {
+		p.die("auto env: cannot be defined with empty prefix")
+	}
+	if p.Parsed() {
+		p.die("auto env: cannot define post-parse")
+	}
+	p.autoEnv = true
+	p.autoEnvPrefix = prefix
+}
+
+// BindEnv binds the given flag to environment variables. Unless the flag is
+// given by a source of higher precedence, such as the command line, see
+// SetPrecedence(), its value is taken from the first of the environment
+// variables that is set and non-empty, before any checks such as required
+// flags are enforced by ParseArgs(). A flag set from an environment variable
+// counts as given by all checks, as if given on the command line, e.g. it
+// conflicts with the other flags of MutuallyExclusive(). The variables are
+// displayed in the help text, see EnvAnnotation.
+func (p *ArgParser) BindEnv(name string, envVars ...string) {
+	flag := p.Lookup(name)
+	if flag == nil {
+		p.die("bind env: undefined flag: %s", name)
+	}
+	if len(envVars) == 0 {
+		p.die("bind env: %s: at least one environment variable is needed", name)
+	}
+	if p.Parsed() {
+		p.die("bind env: %s: cannot define post-parse", name)
+	}
+	if p.envs == nil {
+		p.envs = map[string][]string{}
+	}
+	p.envs[name] = append(p.envs[name], envVars...)
+	for _, envVar := range envVars {
+		if !slices.Contains(flag.Annotations[EnvAnnotation], envVar) {
+			p.SetAnnotation(name, EnvAnnotation, append(flag.Annotations[EnvAnnotation], envVar))
+		}
+	}
+}
+
+// ExpandEnv defines that environment variables in the values of the given
+// string, string slice or string array arguments are expanded by ParseArgs(),
+// before any checks such as allowed values are enforced. Both $VAR and ${VAR}
+// are expanded, as by os.Expand(), and $$ is expanded to a literal $.
+// Variables from dotenv files are included, see DotEnvFile().
+func (p *ArgParser) ExpandEnv(names ...string) {
+	for _, name := range names {
+		p.checkAllowTarget("expand env", name, "string", "stringSlice", "stringArray")
+	}
+	p.expandEnvs = append(p.expandEnvs, names...)
+}
+
+// NoAutoEnv excludes the given flags from being bound to environment
+// variables by AutoEnv().
+func (p *ArgParser) NoAutoEnv(names ...string) {
+	for _, name := range names {
+		if flag := p.Lookup(name); flag == nil {
+			p.die("no auto env: undefined flag: %s", name)
+		}
+	}
+	p.noAutoEnvs = append(p.noAutoEnvs, names...)
+}
+
+// SetExpandEnv sets whether environment variables are expanded in the values
+// of all string, string slice and string array arguments, see ExpandEnv().
+func (p *ArgParser) SetExpandEnv(enabled bool) {
+	p.expandEnvAll = enabled
+}
+
+// autoEnvVar returns the environment variable the given flag is bound to by
+// AutoEnv(), or an empty string.
+func (p *ArgParser) autoEnvVar(name string) string {
+	if !p.autoEnv || name == "help" || name == "help-advanced" ||
+		slices.Contains(p.noAutoEnvs, name) {
+		return ""
+	}
+	name = p.autoEnvPrefix + "_" + name
+	return strings.ToUpper(strings.NewReplacer("-", "_", ".", "_").Replace(name))
+}
+
+// envVars returns the environment variables the given flag is bound to, in
+// the order they are looked up.
+func (p *ArgParser) envVars(name string) []string {
+	envVars := p.envs[name]
+	if envVar := p.autoEnvVar(name); envVar != "" && !slices.Contains(envVars, envVar) {
+		envVars = append(slices.Clip(envVars), envVar)
+	}
+	return envVars
+}
+
+// helpEnvVars returns the environment variables displayed for the given flag,
+// see EnvAnnotation.
+func (p *ArgParser) helpEnvVars(flag *pflag.Flag) []string {
+	envVars := flag.Annotations[EnvAnnotation]
+	if envVar := p.autoEnvVar(flag.Name); envVar != "" && !slices.Contains(envVars, envVar) {
+		envVars = append(slices.Clip(envVars), envVar)
+	}
+	return envVars
+}
+
+// parseEnv sets the flags not set by a source of higher precedence from
+// their bound environment variables.
+func (p *ArgParser) parseEnv() error {
+	err := p.parseDotEnvFiles()
+	p.VisitAll(func(flag *pflag.Flag) {
+		if _, ok := p.valueSources[flag.Name]; err != nil || ok {
+			return
+		}
+		for _, envVar := range p.envVars(flag.Name) {
+			v := p.getenv(envVar)
+			if v == "" {
+				continue
+			}
+			if e := p.setValue(flag, SourceEnv, v); e != nil {
+				err = p.errorf("error.env", envVar, e)
+			}
+			return
+		}
+	})
+	return err
+}
+
+// parseExpandEnv expands environment variables in the argument values, see
+// ExpandEnv().
+func (p *ArgParser) parseExpandEnv() {
+	expand := func(s string) string {
+		return os.Expand(s, func(name string) string {
+			if name == "$" {
+				return "$"
+			}
+			return p.getenv(name)
+		})
+	}
+	for _, pos := range p.pos {
+		if p.expandEnvAll || slices.Contains(p.expandEnvs, pos.name) {
+			*pos.target = expand(*pos.target)
+		}
+	}
+	if p.posN != nil && (p.expandEnvAll || slices.Contains(p.expandEnvs, p.posN.name)) {
+		values := make([]string, len(*p.posN.target))
+		for i, v := range *p.posN.target {
+			values[i] = expand(v)
+		}
+		*p.posN.target = values
+	}
+	p.VisitAll(func(flag *pflag.Flag) {
+		if !p.expandEnvAll && !slices.Contains(p.expandEnvs, flag.Name) {
+			return
+		}
+		switch flag.Value.Type() {
+		case "string":
+			flag.Value.Set(expand(flag.Value.String()))
+		case "stringSlice", "stringArray":
+			sv := flag.Value.(pflag.SliceValue)
+			values := sv.GetSlice()
+			for i := range values {
+				values[i] = expand(values[i])
+			}
+			sv.Replace(values)
+		}
+	})
+}
@@ -0,0 +1,153 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"testing"
+)
+
+func TestAutoEnvFail(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	testError(
+		t, p.Define(func() { p.AutoEnv("") }),
+		"testprog: auto env: cannot be defined with empty prefix",
+	)
+}
+
+func TestAutoEnvOK(t *testing.T) {
+	p := NewArgParser("testprog")
+	p.AutoEnv("TESTPROG")
+
+	var a string
+	p.StringVar(&a, "a-test", "", "usage-a")
+	var b string
+	p.StringVar(&b, "b.test", "", "usage-b")
+	p.BindEnv("b.test", "TESTPROG_B")
+	var c string
+	p.StringVar(&c, "c-test", "", "usage-c")
+	p.NoAutoEnv("c-test")
+	t.Setenv("TESTPROG_A_TEST", "env-a")
+	t.Setenv("TESTPROG_B_TEST", "env-b-auto")
+	t.Setenv("TESTPROG_B", "env-b")
+	t.Setenv("TESTPROG_C_TEST", "env-c")
+	args := []string{}
+	testNoError(t, p.ParseArgs(args))
+	if a != "env-a" || b != "env-b" || c != "" {
+		t.Fatalf("unexpected values: %q, %q, %q", a, b, c)
+	}
+	expected := `usage: testprog [flag]..
+
+flags:
+  -h, --help            display this help text and exit
+      --a-test string   usage-a [env: TESTPROG_A_TEST]
+      --b.test string   usage-b [env: TESTPROG_B, TESTPROG_B_TEST]
+      --c-test string   usage-c
+`
+	if help := p.Help(); help != expected {
+		t.Fatalf("expected help:\n%s\ngot:\n%s", expected, help)
+	}
+}
+
+func TestBindEnvFail(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a int
+	p.IntVar(&a, "a-test", 0, "usage-a")
+	p.BindEnv("a-test", "TESTPROG_A")
+	t.Setenv("TESTPROG_A", "x")
+	args := []string{}
+	testError(
+		t, p.ParseArgs(args),
+		`environment variable TESTPROG_A: invalid argument "x" for "--a-test" flag: `+
+			`strconv.ParseInt: parsing "x": invalid syntax`,
+	)
+
+	p = NewArgParser("testprog")
+	p.IntVar(&a, "a-test", 0, "usage-a")
+	p.BindEnv("a-test", "TESTPROG_A")
+	var b int
+	p.IntVar(&b, "b-test", 0, "usage-b")
+	p.MutuallyExclusive("a-test", "b-test")
+	t.Setenv("TESTPROG_A", "1")
+	args = []string{"--b-test", "2"}
+	testError(t, p.ParseArgs(args), "a-test and b-test are mutually exclusive flags")
+}
+
+func TestBindEnvOK(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVar(&a, "a-test", "", "usage-a")
+	p.BindEnv("a-test", "TESTPROG_A", "TESTPROG_A2")
+	p.Required("a-test")
+	p.StringAllowOptions(&a, "a-test", []string{"x", "y"})
+	var b string
+	p.StringVar(&b, "b-test", "default-b", "usage-b")
+	p.BindEnv("b-test", "TESTPROG_B")
+	t.Setenv("TESTPROG_A", "")
+	t.Setenv("TESTPROG_A2", "x")
+	t.Setenv("TESTPROG_B", "env-b")
+	args := []string{"--b-test=arg-b"}
+	testNoError(t, p.ParseArgs(args))
+	if a != "x" || b != "arg-b" {
+		t.Fatalf("unexpected values: %q, %q", a, b)
+	}
+	expected := `usage: testprog [flag]..
+
+flags:
+  -h, --help            display this help text and exit
+      --a-test string   usage-a (required; one of: x, y) [env: TESTPROG_A, TESTPROG_A2]
+      --b-test string   usage-b [env: TESTPROG_B] (default "default-b")
+`
+	if help := p.Help(); help != expected {
+		t.Fatalf("expected help:\n%s\ngot:\n%s", expected, help)
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVar(&a, "a-test", "", "usage-a")
+	var b []string
+	p.StringSliceVar(&b, "b-test", nil, "usage-b")
+	var c string
+	p.StringVar(&c, "c-test", "", "usage-c")
+	var pos string
+	p.StringPosVar(&pos, "pos", "usage-pos")
+	p.ExpandEnv("a-test", "b-test", "pos")
+	t.Setenv("TESTPROG_DIR", "/home/test")
+	args := []string{
+		"--a-test", "$TESTPROG_DIR/.cache", "--b-test", "${TESTPROG_DIR}x,$$TESTPROG_DIR",
+		"--c-test", "$TESTPROG_DIR", "$TESTPROG_UNSET-pos",
+	}
+	testNoError(t, p.ParseArgs(args))
+	if a != "/home/test/.cache" || c != "$TESTPROG_DIR" || pos != "-pos" {
+		t.Fatalf("unexpected values: %q, %q, %q", a, c, pos)
+	}
+	if len(b) != 2 || b[0] != "/home/testx" || b[1] != "$TESTPROG_DIR" {
+		t.Fatalf("unexpected values: %q", b)
+	}
+}
+
+func TestSetExpandEnv(t *testing.T) {
+	p := NewArgParser("testprog")
+	p.SetExpandEnv(true)
+
+	var a string
+	p.StringVar(&a, "a-test", "$TESTPROG_DIR", "usage-a")
+	var b []string
+	p.StringArrayVar(&b, "b-test", nil, "usage-b")
+	var pos []string
+	p.StringPosNVar(&pos, "pos", "usage-pos", 0, -1)
+	t.Setenv("TESTPROG_DIR", "/home/test")
+	args := []string{"--b-test", "${TESTPROG_DIR}/b", "$TESTPROG_DIR/pos"}
+	testNoError(t, p.ParseArgs(args))
+	if a != "/home/test" || len(b) != 1 || b[0] != "/home/test/b" ||
+		len(pos) != 1 || pos[0] != "/home/test/pos" {
+		t.Fatalf("unexpected values: %q, %q, %q", a, b, pos)
+	}
+}
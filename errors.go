@@ -0,0 +1,166 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorHandling defines how ParseArgs() handles errors, see NewArgParser().
+type ErrorHandling int
+
+const (
+	// ContinueOnError returns the error, which is the default.
+	ContinueOnError ErrorHandling = iota
+	// ExitOnError prints the error, see FormatError(), followed by the usage
+	// lines of the help text unless set otherwise using SetErrorUsage(), to
+	// the error output and exits with status 2.
+	ExitOnError
+	// PanicOnError panics with the error.
+	PanicOnError
+)
+
+// ErrorUsage defines what ParseArgs() prints following an error, see
+// SetErrorUsage().
+type ErrorUsage int
+
+const (
+	// ErrorUsageNone prints nothing following the error.
+	ErrorUsageNone ErrorUsage = iota + 1
+	// ErrorUsageShort prints the usage lines of the help text.
+	ErrorUsageShort
+	// ErrorUsageHelp prints the help text.
+	ErrorUsageHelp
+)
+
+// ChoiceError is returned by ParseArgs() when a value is not among the allowed
+// options, see StringAllowOptions() and StringSliceAllowOptions().
+type ChoiceError struct {
+	Flag    string
+	Value   string
+	Options []string
+	err     error
+}
+
+// MutualExclusionError is returned by ParseArgs() when flags that are
+// mutually exclusive are given together, see MutuallyExclusive() and
+// ExactlyOneRequired().
+type MutualExclusionError struct {
+	// Flags are the flags given together.
+	Flags []string
+	err   error
+}
+
+// RequiredError is returned by ParseArgs() when required flags are not given,
+// see Required(), RequiredIf(), RequiredIfSet(), RequiredTogether(),
+// AtLeastOneRequired(), ExactlyOneRequired() and DependsOn().
+type RequiredError struct {
+	// Flags are the flags missing, or for AtLeastOneRequired() and
+	// ExactlyOneRequired(), the flags of which one is required.
+	Flags []string
+	err   error
+}
+
+// checkErrors collects the errors of the checks enforced by ParseArgs().
+type checkErrors struct {
+	all  bool
+	errs []error
+}
+
+// FormatError returns the given error formatted as printed by the parser,
+// using the function set by SetErrorFormatter(), or by default prefixed with
+// the program name, e.g. "prog: missing required flag: name".
+func (p *ArgParser) FormatError(err error) string {
+	if p.errorFormatter != nil {
+		return p.errorFormatter(err)
+	}
+	return fmt.Sprintf("%s: %v", p.Name, err)
+}
+
+// SetAllErrors sets whether ParseArgs() enforces all checks, such as required
+// flags, allowed values and the number of positional arguments, and returns
+// every failure joined using errors.Join, instead of returning the first
+// failure only. Errors parsing the command line and reading the sources of
+// values are still returned directly, and the functions added using
+// ValidateAfter() are only run if all other checks pass.
+func (p *ArgParser) SetAllErrors(enabled bool) {
+	p.allErrors = enabled
+}
+
+// SetErrorUsage sets what ParseArgs() prints to the error output following an
+// error. If set to ErrorUsageShort or ErrorUsageHelp, ParseArgs() prints errors
+// also when returning them, as with the default ContinueOnError. By default,
+// errors are only printed with ExitOnError, followed by the usage lines.
+func (p *ArgParser) SetErrorUsage(usage ErrorUsage) {
+	if usage < ErrorUsageNone || usage > ErrorUsageHelp {
+		p.die("set error usage: unknown error usage: %d", usage)
+	}
+	p.errorUsage = usage
+}
+
+// SetErrorFormatter sets the function formatting the errors printed by the
+// parser, e.g. to add colors, see FormatError().
+func (p *ArgParser) SetErrorFormatter(fn func(err error) string) {
+	p.errorFormatter = fn
+}
+
+// checkErrors returns a collector of check errors.
+func (p *ArgParser) checkErrors() *checkErrors {
+	return &checkErrors{all: p.allErrors}
+}
+
+func (e *ChoiceError) Error() string {
+	return e.err.Error()
+}
+
+func (e *ChoiceError) Unwrap() error {
+	return e.err
+}
+
+func (e *MutualExclusionError) Error() string {
+	return e.err.Error()
+}
+
+func (e *MutualExclusionError) Unwrap() error {
+	return e.err
+}
+
+func (e *RequiredError) Error() string {
+	return e.err.Error()
+}
+
+func (e *RequiredError) Unwrap() error {
+	return e.err
+}
+
+// printError prints the given error to the error output, followed by the
+// usage lines or help text as set using SetErrorUsage().
+func (p *ArgParser) printError(err error) {
+	fmt.Fprintln(p.errOut(), p.FormatError(err))
+	switch p.errorUsage {
+	case 0, ErrorUsageShort:
+		fmt.Fprint(p.errOut(), p.shortUsage())
+	case ErrorUsageHelp:
+		p.generateHelp(p.errOut(), false, p.width(p.errOut()))
+	}
+}
+
+// add adds the given error, if not nil, and returns whether checking should
+// stop.
+func (c *checkErrors) add(err error) bool {
+	if err != nil {
+		c.errs = append(c.errs, err)
+	}
+	return len(c.errs) > 0 && !c.all
+}
+
+// err returns the error collected, or the errors joined if multiple.
+func (c *checkErrors) err() error {
+	if len(c.errs) == 1 {
+		return c.errs[0]
+	}
+	return errors.Join(c.errs...)
+}
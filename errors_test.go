@@ -0,0 +1,181 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestChoiceError(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a []string
+	p.StringSliceVar(&a, "a-test", nil, "usage-a")
+	p.StringSliceAllowOptions(&a, "a-test", []string{"x", "y"})
+	args := []string{"--a-test", "x,z"}
+	err := p.ParseArgs(args)
+	testError(t, err, `a-test[1]: invalid value: "z" is not among options: ["x" "y"]`)
+	var cerr *ChoiceError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("expected ChoiceError, got: %T", err)
+	}
+	if cerr.Flag != "a-test" || cerr.Value != "z" || !slices.Equal(cerr.Options, []string{"x", "y"}) {
+		t.Fatalf("unexpected error fields: %q, %q, %q", cerr.Flag, cerr.Value, cerr.Options)
+	}
+}
+
+func TestErrorHandling(t *testing.T) {
+	p := NewArgParser("testprog", PanicOnError)
+
+	var a string
+	p.StringVar(&a, "a-test", "", "usage-a")
+	p.Required("a-test")
+	defer func() {
+		err, ok := recover().(error)
+		if !ok {
+			t.Fatalf("expected panic with error")
+		}
+		testError(t, err, "missing required flag: a-test")
+	}()
+	args := []string{}
+	p.ParseArgs(args)
+	t.Fatalf("expected panic")
+}
+
+func TestFormatError(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	err := errors.New("some error")
+	if s := p.FormatError(err); s != "testprog: some error" {
+		t.Fatalf("unexpected formatted error: %q", s)
+	}
+	p.SetErrorFormatter(func(err error) string {
+		return "error: " + err.Error() + "!"
+	})
+	if s := p.FormatError(err); s != "error: some error!" {
+		t.Fatalf("unexpected formatted error: %q", s)
+	}
+}
+
+func TestMutualExclusionError(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a, b, c bool
+	p.BoolVar(&a, "a-test", false, "usage-a")
+	p.BoolVar(&b, "b-test", false, "usage-b")
+	p.BoolVar(&c, "c-test", false, "usage-c")
+	p.ExactlyOneRequired("a-test", "b-test", "c-test")
+	args := []string{"--a-test", "--c-test"}
+	err := p.ParseArgs(args)
+	var merr *MutualExclusionError
+	if !errors.As(err, &merr) {
+		t.Fatalf("expected MutualExclusionError, got: %T", err)
+	}
+	if !slices.Equal(merr.Flags, []string{"a-test", "c-test"}) {
+		t.Fatalf("unexpected error flags: %q", merr.Flags)
+	}
+}
+
+func TestRequiredError(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a, b, c string
+	p.StringVar(&a, "a-test", "", "usage-a")
+	p.StringVar(&b, "b-test", "", "usage-b")
+	p.StringVar(&c, "c-test", "", "usage-c")
+	p.Required("a-test")
+	p.Required("c-test")
+	args := []string{"--b-test", "x"}
+	err := p.ParseArgs(args)
+	testError(t, err, "missing required flags: a-test, c-test")
+	var rerr *RequiredError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("expected RequiredError, got: %T", err)
+	}
+	if !slices.Equal(rerr.Flags, []string{"a-test", "c-test"}) {
+		t.Fatalf("unexpected error flags: %q", rerr.Flags)
+	}
+}
+
+func TestSetAllErrors(t *testing.T) {
+	p := NewArgParser("testprog")
+	p.SetAllErrors(true)
+
+	var a, b, c string
+	p.StringVar(&a, "a-test", "", "usage-a")
+	p.StringVar(&b, "b-test", "", "usage-b")
+	p.StringVar(&c, "c-test", "x", "usage-c")
+	p.Required("a-test")
+	p.StringAllowOptions(&b, "b-test", []string{"x", "y"})
+	p.StringAllowOptions(&c, "c-test", []string{"x", "y"})
+	args := []string{"--b-test", "z", "--c-test", "z"}
+	err := p.ParseArgs(args)
+	testError(
+		t, err,
+		"missing required flag: a-test\n"+
+			`b-test: invalid value: "z" is not among options: ["x" "y"]`+"\n"+
+			`c-test: invalid value: "z" is not among options: ["x" "y"]`,
+	)
+	var rerr *RequiredError
+	if !errors.As(err, &rerr) {
+		t.Fatalf("expected RequiredError, got: %T", err)
+	}
+
+	p.SetAllErrors(false)
+	err = p.ParseArgs(args)
+	testError(t, err, "missing required flag: a-test")
+
+	p = NewArgParser("testprog")
+	p.SetAllErrors(true)
+	p.StringVar(&a, "a-test", "", "usage-a")
+	p.Required("a-test")
+	called := false
+	p.ValidateAfter(func(p *ArgParser) error {
+		called = true
+		return nil
+	})
+	err = p.ParseArgs([]string{"x"})
+	testError(t, err, "no positional arguments expected\nmissing required flag: a-test")
+	if called {
+		t.Fatalf("unexpected call of validate after func")
+	}
+}
+
+func TestSetErrorUsage(t *testing.T) {
+	p := NewArgParser("testprog")
+	p.SetErrorUsage(ErrorUsageShort)
+	var b strings.Builder
+	p.SetErrOutput(&b)
+
+	var a string
+	p.StringVar(&a, "a-test", "", "usage-a")
+	p.Required("a-test")
+	args := []string{}
+	testError(t, p.ParseArgs(args), "missing required flag: a-test")
+	expected := `testprog: missing required flag: a-test
+usage: testprog [flag]..
+`
+	if b.String() != expected {
+		t.Fatalf("expected output:\n%s\ngot:\n%s", expected, b.String())
+	}
+
+	b.Reset()
+	p.SetErrorUsage(ErrorUsageHelp)
+	testError(t, p.ParseArgs(args), "missing required flag: a-test")
+	expected = "testprog: missing required flag: a-test\n" + p.Help()
+	if b.String() != expected {
+		t.Fatalf("expected output:\n%s\ngot:\n%s", expected, b.String())
+	}
+
+	b.Reset()
+	p.SetErrorUsage(ErrorUsageNone)
+	testError(t, p.ParseArgs(args), "missing required flag: a-test")
+	if b.String() != "" {
+		t.Fatalf("unexpected output:\n%s", b.String())
+	}
+}
@@ -0,0 +1,740 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/pflag"
+)
+
+// EnvAnnotation is the flag annotation key for the names of environment
+// variables associated with a flag, which are displayed in the help text.
+// It is set by BindEnv(), or can be set using FlagSet's SetAnnotation() for
+// variables that are only to be displayed.
+const EnvAnnotation = "argparse_env"
+
+// ErrHelp is returned by ParseArgs() when -h/--help or --help-advanced is
+// given and exiting on help is disabled using SetExitOnHelp(false). The help
+// text requested is then available from RequestedHelp(). It is the same error
+// as pflag.ErrHelp.
+var ErrHelp = pflag.ErrHelp
+
+// HelpData is the data model available to help templates, see
+// SetHelpTemplate().
+type HelpData struct {
+	// Name is the program name.
+	Name string `json:"name"`
+	// Usage is the generated usage synopsis, e.g. "prog [flag].. dir [file]..".
+	Usage string `json:"usage"`
+	// Usages are the usage lines displayed, i.e. the lines set using
+	// SetUsage(), or otherwise only Usage.
+	Usages []string `json:"usages"`
+	// Description is ArgParser's Description.
+	Description string `json:"description,omitempty"`
+	// Epilog is ArgParser's Epilog.
+	Epilog string `json:"epilog,omitempty"`
+	// Positionals are the positional arguments, in definition order.
+	Positionals []HelpPositional `json:"positionals"`
+	// PositionalWidth is the length of the longest positional argument name.
+	PositionalWidth int `json:"-"`
+	// Flags are the flags not hidden, in help order, see FlagPriority().
+	Flags []HelpFlag `json:"flags"`
+	// FlagUsages is the usage text, as formatted by FlagSet, of the flags not
+	// assigned to a section.
+	FlagUsages string `json:"-"`
+	// FlagSections are the flag sections, in definition order.
+	FlagSections []HelpFlagSection `json:"-"`
+	// Examples are the examples, in definition order, see AddExample().
+	Examples []HelpExample `json:"examples,omitempty"`
+	// Constraints are descriptions of defined constraints, such as required
+	// and mutually exclusive flags.
+	Constraints []string `json:"constraints,omitempty"`
+	// Messages is the message catalog, see SetMessages(), including all
+	// messages of DefaultMessages. Labels in help texts are looked up here.
+	Messages Messages `json:"-"`
+	// Width is the width text is wrapped to, or 0 for no wrapping.
+	Width int `json:"-"`
+}
+
+// HelpExample describes an example in HelpData.
+type HelpExample struct {
+	Cmdline     string `json:"cmdline"`
+	Description string `json:"description,omitempty"`
+}
+
+// HelpFlag describes a flag in HelpData.
+type HelpFlag struct {
+	Name        string   `json:"name"`
+	Shorthand   string   `json:"shorthand,omitempty"`
+	Type        string   `json:"type"`
+	Default     string   `json:"default"`
+	Usage       string   `json:"usage"`
+	Required    bool     `json:"required"`
+	Advanced    bool     `json:"advanced"`
+	Section     string   `json:"section,omitempty"`
+	Env         []string `json:"env,omitempty"`
+	Constraints []string `json:"constraints,omitempty"`
+}
+
+// HelpFlagSection describes a flag section in HelpData, see FlagSection().
+type HelpFlagSection struct {
+	Name       string
+	FlagUsages string
+}
+
+// HelpPositional describes a positional argument in HelpData. Min and Max
+// are the number of values accepted, where Max is -1 for no limit.
+type HelpPositional struct {
+	Name        string   `json:"name"`
+	Usage       string   `json:"usage"`
+	Min         int      `json:"min"`
+	Max         int      `json:"max"`
+	Constraints []string `json:"constraints,omitempty"`
+}
+
+// defaultTextValue wraps a flag's Value for FlagSet's usage formatting, which
+// then regards the default text as the value, and does not display it if it
+// is empty, regardless of the value type.
+type defaultTextValue struct {
+	pflag.Value
+	text string
+}
+
+func (v defaultTextValue) String() string {
+	return v.text
+}
+
+// helpValue is the value of the -h/--help flag, which acts as a bool flag, but
+// also accepts "json" for requesting the help as JSON. Given twice, e.g. -hh,
+// the advanced help is requested.
+type helpValue struct {
+	help     bool
+	advanced bool
+	format   string
+}
+
+func (v *helpValue) IsBoolFlag() bool {
+	return true
+}
+
+func (v *helpValue) Set(s string) error {
+	if s == "json" {
+		v.help = true
+		v.format = s
+		return nil
+	}
+	help, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	v.advanced = v.advanced || (help && v.help)
+	v.help = help
+	v.format = ""
+	return nil
+}
+
+func (v *helpValue) String() string {
+	return strconv.FormatBool(v.help)
+}
+
+func (v *helpValue) Type() string {
+	return "bool"
+}
+
+type flagSection struct {
+	name  string
+	names []string
+}
+
+// DefaultHelpTemplate is the template used for the help text unless another
+// one is set using SetHelpTemplate().
+const DefaultHelpTemplate = `{{range $i, $usage := .Usages -}}
+{{if $i}}   {{index $.Messages "help.usage-or"}}{{else}}{{index $.Messages "help.usage"}}{{end}} {{$usage}}
+{{end}}
+{{if .Description}}{{wrap .Width 0 .Description}}
+
+{{end}}{{if .Positionals}}{{index .Messages "help.positionals"}}
+{{range .Positionals}}  {{pad .Name $.PositionalWidth}}   {{wrap $.Width (add $.PositionalWidth 5) .Usage}}
+{{end}}
+{{end}}{{index .Messages "help.flags"}}
+{{.FlagUsages}}{{range .FlagSections}}
+{{.Name}}:
+{{.FlagUsages}}{{end}}{{if .Examples}}
+{{index .Messages "help.examples"}}
+{{range .Examples}}  {{.Cmdline}}
+{{if .Description}}      {{wrap $.Width 6 .Description}}
+{{end}}{{end}}{{end}}{{if .Epilog}}
+{{wrap .Width 0 .Epilog}}
+{{end}}`
+
+var helpTemplateFuncs = template.FuncMap{
+	"add":  func(a, b int) int { return a + b },
+	"join": strings.Join,
+	"pad": func(s string, n int) string {
+		return fmt.Sprintf("%-*s", n, s)
+	},
+	"wrap": wrapText,
+}
+
+var defaultHelpTemplate = template.Must(
+	template.New("help").Funcs(helpTemplateFuncs).Parse(DefaultHelpTemplate),
+)
+
+// Advanced marks the given flags as advanced, which the help text omits unless
+// the advanced help is requested using --help-advanced or -hh. The
+// --help-advanced flag is added when the first advanced flag is marked.
+func (p *ArgParser) Advanced(names ...string) {
+	for _, name := range names {
+		if flag := p.Lookup(name); flag == nil {
+			p.die("advanced: undefined flag: %s", name)
+		}
+	}
+	if len(p.advanced) == 0 && len(names) > 0 && p.Lookup("help-advanced") == nil {
+		p.BoolVar(&p.help.advanced, "help-advanced", false, p.message("help.flag-advanced"))
+	}
+	p.advanced = append(p.advanced, names...)
+}
+
+// AddExample adds an example command line, with an optional description of
+// what it does, to the examples displayed at the end of the help text.
+func (p *ArgParser) AddExample(cmdline, description string) {
+	if cmdline == "" {
+		p.die("example: cannot be defined with empty command line")
+	}
+	p.examples = append(p.examples, HelpExample{cmdline, description})
+}
+
+// FlagPriority sets the priority of the given flag in the help text, where
+// flags with higher priority are displayed first. Flags have priority 0 by
+// default, and flags with equal priority are displayed in definition order,
+// or alphabetically if the embedded FlagSet's SortFlags is true.
+func (p *ArgParser) FlagPriority(name string, priority int) {
+	if flag := p.Lookup(name); flag == nil {
+		p.die("flag priority: undefined flag: %s", name)
+	}
+	if p.flagPriorities == nil {
+		p.flagPriorities = map[string]int{}
+	}
+	p.flagPriorities[name] = priority
+}
+
+// FlagSection assigns the given flags to a named section, such as "output
+// options", which the help text displays under its own header. Sections are
+// displayed in the order they are first defined, after the flags not assigned
+// to any section.
+func (p *ArgParser) FlagSection(section string, names ...string) {
+	if section == "" {
+		p.die("flag section: cannot be defined with empty name")
+	}
+	for _, name := range names {
+		if flag := p.Lookup(name); flag == nil {
+			p.die("flag section: %s: undefined flag: %s", section, name)
+		}
+		if s := p.flagSection(name); s != "" {
+			p.die("flag section: %s: flag already in section %q: %s", section, s, name)
+		}
+	}
+	for i := range p.flagSections {
+		if p.flagSections[i].name == section {
+			p.flagSections[i].names = append(p.flagSections[i].names, names...)
+			return
+		}
+	}
+	p.flagSections = append(p.flagSections, flagSection{section, names})
+}
+
+// Help returns the help text, as displayed by -h/--help. Advanced flags are
+// omitted, see Advanced(). The text is only wrapped if set using
+// SetHelpWidth().
+func (p *ArgParser) Help() string {
+	var b strings.Builder
+	p.generateHelp(&b, false, p.width(nil))
+	return b.String()
+}
+
+// HelpAdvanced returns the help text including advanced flags, as displayed by
+// --help-advanced or -hh. The text is only wrapped if set using
+// SetHelpWidth().
+func (p *ArgParser) HelpAdvanced() string {
+	var b strings.Builder
+	p.generateHelp(&b, true, p.width(nil))
+	return b.String()
+}
+
+// HelpJSON returns a JSON document describing the program's usage, i.e.
+// HelpData, as displayed by --help=json. Advanced flags are included.
+func (p *ArgParser) HelpJSON() string {
+	b, err := json.MarshalIndent(p.helpData(true, 0), "", "  ")
+	if err != nil {
+		p.die("help json: %v", err)
+	}
+	return string(b) + "\n"
+}
+
+// RequestedHelp returns the help in the format requested using -h/--help,
+// i.e. HelpJSON() if --help=json was given, HelpAdvanced() if --help-advanced
+// or -hh was given, and otherwise Help().
+func (p *ArgParser) RequestedHelp() string {
+	return p.requestedHelp(p.width(nil))
+}
+
+// SetDefaultText sets the text displayed as the given flag's default value in
+// the help text, e.g. "auto" for a flag whose empty default means that the
+// value is determined automatically. If text is empty, no default value is
+// displayed, e.g. for a flag whose default is a secret.
+func (p *ArgParser) SetDefaultText(name, text string) {
+	if flag := p.Lookup(name); flag == nil {
+		p.die("default text: undefined flag: %s", name)
+	}
+	if p.defaultTexts == nil {
+		p.defaultTexts = map[string]string{}
+	}
+	p.defaultTexts[name] = text
+}
+
+// SetErrOutput sets the destination for error messages and warnings,
+// defaulting to os.Stderr. This is the errors printed by ParseArgs(), see
+// SetErrorUsage(), and the output of the embedded FlagSet, which prints
+// warnings for deprecated flags.
+func (p *ArgParser) SetErrOutput(w io.Writer) {
+	p.errOutput = w
+	p.FlagSet.SetOutput(w)
+}
+
+// SetExit sets the function used to exit the program, defaulting to os.Exit,
+// e.g. for tests to capture the exit code. If it returns, ParseArgs() returns
+// ErrHelp after printing the help text, and otherwise the error.
+func (p *ArgParser) SetExit(fn func(code int)) {
+	p.exitFunc = fn
+}
+
+// SetExitOnHelp defines whether ParseArgs() prints the help text and exits
+// when -h/--help is given, which is the default. If disabled, ParseArgs()
+// instead returns ErrHelp, leaving it to the caller to display
+// RequestedHelp() and exit.
+func (p *ArgParser) SetExitOnHelp(exit bool) {
+	p.exitOnHelp = exit
+}
+
+// SetHelpTemplate sets a text/template template used for rendering the help
+// text, executed with *HelpData. Besides the standard functions, "add" adds
+// two integers, "join" is strings.Join, "pad" left justifies a string to a
+// width, and "wrap" wraps text to a width with a hanging indent, as in
+// {{wrap .Width 4 .Description}}.
+func (p *ArgParser) SetHelpTemplate(text string) {
+	tmpl, err := template.New("help").Funcs(helpTemplateFuncs).Parse(text)
+	if err != nil {
+		p.die("help template: %v", err)
+	}
+	p.helpTemplate = tmpl
+}
+
+// SetHelpWidth sets the width the help text is wrapped to. If width is 0, the
+// default, the help text printed by ParseArgs() is wrapped to the width from
+// the COLUMNS environment variable or of the terminal it is written to, and
+// the text returned by Help() and HelpAdvanced() is not wrapped. If width is
+// negative, the help text is not wrapped.
+func (p *ArgParser) SetHelpWidth(width int) {
+	p.helpWidth = width
+}
+
+// SetOutput sets the destination for the help text printed by ParseArgs()
+// when requested, defaulting to os.Stdout. Note that this shadows FlagSet's
+// SetOutput(), see SetErrOutput().
+func (p *ArgParser) SetOutput(w io.Writer) {
+	p.output = w
+}
+
+// SetUsage sets the usage lines displayed in the help text, replacing the
+// generated usage synopsis. Multiple lines describe alternative forms of
+// invocation, e.g. "prog [flag].. file" and "prog --list".
+func (p *ArgParser) SetUsage(lines ...string) {
+	p.usages = lines
+}
+
+// constraintHints returns short descriptions of the constraints defined for
+// the given argument's value, for use next to its usage in help texts. Custom
+// checks, such as of AllowFunc() and Attach(), and port numbers are not
+// described.
+func (p *ArgParser) constraintHints(name string) []string {
+	var hints []string
+	if slices.Contains(p.required, name) {
+		hints = append(hints, p.message("hint.required"))
+	}
+	for _, a := range p.allowedOptions {
+		if a.name == name && a.optionsFunc == nil {
+			hints = append(hints, p.message("hint.options", strings.Join(a.options, ", ")))
+		}
+	}
+	for _, a := range p.allowedRegexps {
+		if a.name == name {
+			hints = append(hints, p.message("hint.regexp", a.regexp))
+		}
+	}
+	for _, a := range p.allowedIntRanges {
+		if a.name == name {
+			hints = append(hints, p.message("hint.range", a.min, a.max))
+		}
+	}
+	for _, a := range p.allowedFloatRanges {
+		if a.name == name {
+			hints = append(hints, p.message(floatRangeMessage("hint", a), a.min, a.max))
+		}
+	}
+	for _, a := range p.allowedIntFuncs {
+		if a.name == name && a.hint != "" {
+			hints = append(hints, p.message("hint."+a.hint))
+		}
+	}
+	for _, a := range p.allowedFloatFuncs {
+		if a.name == name && a.hint != "" {
+			hints = append(hints, p.message("hint."+a.hint))
+		}
+	}
+	for _, d := range p.deniedOptions {
+		if d.name == name {
+			hints = append(hints, p.message("hint.denied", strings.Join(d.options, ", ")))
+		}
+	}
+	for _, d := range p.deniedRegexps {
+		if d.name == name {
+			hints = append(hints, p.message("hint.denied-regexp", d.regexp))
+		}
+	}
+	return hints
+}
+
+// constraints returns descriptions of the constraints defined for the
+// arguments, for use in help texts.
+func (p *ArgParser) constraints() []string {
+	var c []string
+	for _, name := range p.required {
+		c = append(c, p.message("constraint.required", name))
+	}
+	for _, r := range p.requiredIfs {
+		if r.anyValue {
+			c = append(c, p.message("constraint.required-if-set", r.name, r.otherName))
+		} else {
+			c = append(c, p.message(
+				"constraint.required-if", r.name, r.otherName, r.otherValue,
+			))
+		}
+	}
+	for _, names := range p.requiredTogethers {
+		c = append(c, p.message("constraint.required-together", strings.Join(names, ", ")))
+	}
+	for _, names := range p.atLeastOnes {
+		c = append(c, p.message("constraint.at-least-one", strings.Join(names, ", ")))
+	}
+	for _, names := range p.mutuallyExclusives {
+		c = append(c, p.message("constraint.mutually-exclusive", strings.Join(names, ", ")))
+	}
+	for _, names := range p.exactlyOnes {
+		c = append(c, p.message("constraint.exactly-one", strings.Join(names, ", ")))
+	}
+	for _, d := range p.dependsOns {
+		c = append(c, p.message("constraint.depends-on", d.name, d.otherName))
+	}
+	for _, a := range p.allowedRegexps {
+		c = append(c, p.message("constraint.regexp", a.name, a.regexp))
+	}
+	for _, a := range p.allowedOptions {
+		if a.optionsFunc == nil {
+			c = append(c, p.message("constraint.options", a.name, strings.Join(a.options, ", ")))
+		}
+	}
+	for _, a := range p.allowedIntRanges {
+		c = append(c, p.message("constraint.range", a.name, a.min, a.max))
+	}
+	for _, a := range p.allowedFloatRanges {
+		c = append(c, p.message(floatRangeMessage("constraint", a), a.name, a.min, a.max))
+	}
+	for _, a := range p.allowedIntFuncs {
+		if a.hint != "" {
+			c = append(c, p.message("constraint."+a.hint, a.name))
+		}
+	}
+	for _, a := range p.allowedFloatFuncs {
+		if a.hint != "" {
+			c = append(c, p.message("constraint."+a.hint, a.name))
+		}
+	}
+	for _, d := range p.deniedOptions {
+		c = append(c, p.message("constraint.denied", d.name, strings.Join(d.options, ", ")))
+	}
+	for _, d := range p.deniedRegexps {
+		c = append(c, p.message("constraint.denied-regexp", d.name, d.regexp))
+	}
+	return c
+}
+
+// floatRangeMessage returns the ID of the message describing the given float
+// range, with the given prefix, "hint" or "constraint".
+func floatRangeMessage(prefix string, a allowedFloat64Range) string {
+	if a.exclusive {
+		return prefix + ".range-float-exclusive"
+	}
+	return prefix + ".range-float"
+}
+
+// flagSection returns the name of the section the given flag is assigned to,
+// or an empty string.
+func (p *ArgParser) flagSection(name string) string {
+	for _, section := range p.flagSections {
+		if slices.Contains(section.names, name) {
+			return section.name
+		}
+	}
+	return ""
+}
+
+func (p *ArgParser) generateHelp(w io.Writer, advanced bool, width int) {
+	tmpl := p.helpTemplate
+	if tmpl == nil {
+		tmpl = defaultHelpTemplate
+	}
+	if err := tmpl.Execute(w, p.helpData(advanced, width)); err != nil {
+		p.die("help template: %v", err)
+	}
+}
+
+func (p *ArgParser) helpData(advanced bool, width int) *HelpData {
+	data := HelpData{
+		Name:        p.Name,
+		Description: p.Description,
+		Epilog:      p.Epilog,
+		Positionals: []HelpPositional{},
+		Flags:       []HelpFlag{},
+		Messages:    p.messagesAll(),
+		Width:       width,
+	}
+
+	posArgs := ""
+	for _, pos := range p.pos {
+		posArgs = posArgs + " " + pos.name
+		data.Positionals = append(
+			data.Positionals, HelpPositional{
+				pos.name, p.positionalUsage(pos.name, pos.usage), 1, 1, p.constraintHints(pos.name),
+			},
+		)
+	}
+
+	if p.posN != nil {
+		if p.posN.minN == 0 {
+			posArgs = posArgs + " [" + p.posN.name + "]"
+		}
+		for i := 1; i <= p.posN.minN; i++ {
+			posArgs = posArgs + " " + p.posN.name
+		}
+		if p.posN.maxN == -1 {
+			posArgs = posArgs + ".."
+		} else {
+			for i := p.posN.minN; i < p.posN.maxN; i++ {
+				posArgs = posArgs + " " + "[" + p.posN.name
+			}
+			for i := p.posN.minN; i < p.posN.maxN; i++ {
+				posArgs = posArgs + "]"
+			}
+		}
+		data.Positionals = append(
+			data.Positionals,
+			HelpPositional{
+				p.posN.name, p.positionalUsage(p.posN.name, p.posN.usage),
+				p.posN.minN, p.posN.maxN, p.constraintHints(p.posN.name),
+			},
+		)
+	}
+
+	for _, pos := range data.Positionals {
+		if len(pos.Name) > data.PositionalWidth {
+			data.PositionalWidth = len(pos.Name)
+		}
+	}
+
+	data.Usage = fmt.Sprintf("%s [flag]..%s", p.Name, posArgs)
+	data.Usages = p.usages
+	if len(data.Usages) == 0 {
+		data.Usages = []string{data.Usage}
+	}
+
+	sections := map[string]*pflag.FlagSet{}
+	for _, flag := range p.helpFlags(advanced) {
+		section := p.flagSection(flag.Name)
+		data.Flags = append(data.Flags, HelpFlag{
+			Name:        flag.Name,
+			Shorthand:   flag.Shorthand,
+			Type:        flag.Value.Type(),
+			Default:     flag.DefValue,
+			Usage:       flag.Usage,
+			Required:    slices.Contains(p.required, flag.Name),
+			Advanced:    slices.Contains(p.advanced, flag.Name),
+			Section:     section,
+			Env:         p.helpEnvVars(flag),
+			Constraints: p.constraintHints(flag.Name),
+		})
+		fs, ok := sections[section]
+		if !ok {
+			fs = pflag.NewFlagSet(section, pflag.ContinueOnError)
+			fs.SortFlags = false
+			sections[section] = fs
+		}
+		fs.AddFlag(flag)
+	}
+	if fs, ok := sections[""]; ok {
+		data.FlagUsages = fs.FlagUsagesWrapped(data.Width)
+	}
+	for _, section := range p.flagSections {
+		if fs, ok := sections[section.name]; ok {
+			data.FlagSections = append(
+				data.FlagSections, HelpFlagSection{section.name, fs.FlagUsagesWrapped(data.Width)},
+			)
+		}
+	}
+
+	data.Examples = p.examples
+	data.Constraints = p.constraints()
+
+	return &data
+}
+
+// helpFlags returns the flags in the order they are displayed in help,
+// omitting hidden flags, and advanced flags unless requested. Flags with a default text,
+// environment variables or constraint hints are copies, modified for the help
+// text.
+func (p *ArgParser) helpFlags(advanced bool) []*pflag.Flag {
+	var flags []*pflag.Flag
+	p.VisitAll(func(flag *pflag.Flag) {
+		if flag.Hidden || (!advanced && slices.Contains(p.advanced, flag.Name)) {
+			return
+		}
+		text, hasText := p.defaultTexts[flag.Name]
+		envs := p.helpEnvVars(flag)
+		hints := p.constraintHints(flag.Name)
+		if hasText || len(envs) > 0 || len(hints) > 0 {
+			c := *flag
+			if hasText {
+				c.Value = defaultTextValue{flag.Value, text}
+				c.DefValue = text
+			}
+			if len(hints) > 0 {
+				c.Usage = fmt.Sprintf("%s (%s)", c.Usage, strings.Join(hints, "; "))
+			}
+			if len(envs) > 0 {
+				c.Usage = fmt.Sprintf("%s [%s]", c.Usage, p.message("hint.env", strings.Join(envs, ", ")))
+			}
+			flag = &c
+		}
+		flags = append(flags, flag)
+	})
+	slices.SortStableFunc(flags, func(a, b *pflag.Flag) int {
+		return p.flagPriorities[b.Name] - p.flagPriorities[a.Name]
+	})
+	return flags
+}
+
+func (p *ArgParser) errOut() io.Writer {
+	if p.errOutput == nil {
+		return os.Stderr
+	}
+	return p.errOutput
+}
+
+func (p *ArgParser) exit(code int) {
+	if p.exitFunc == nil {
+		os.Exit(code)
+	}
+	p.exitFunc(code)
+}
+
+func (p *ArgParser) out() io.Writer {
+	if p.output == nil {
+		return os.Stdout
+	}
+	return p.output
+}
+
+// positionalUsage returns the usage of the given positional argument, with
+// its constraint hints.
+func (p *ArgParser) positionalUsage(name, usage string) string {
+	if hints := p.constraintHints(name); len(hints) > 0 {
+		return fmt.Sprintf("%s (%s)", usage, strings.Join(hints, "; "))
+	}
+	return usage
+}
+
+// shortUsage returns the usage lines of the help text.
+func (p *ArgParser) shortUsage() string {
+	var b strings.Builder
+	for i, usage := range p.helpData(false, 0).Usages {
+		if i == 0 {
+			fmt.Fprintf(&b, "%s %s\n", p.message("help.usage"), usage)
+		} else {
+			fmt.Fprintf(&b, "   %s %s\n", p.message("help.usage-or"), usage)
+		}
+	}
+	return b.String()
+}
+
+// requestedHelp returns the help requested, see RequestedHelp(), wrapped to
+// the given width.
+func (p *ArgParser) requestedHelp(width int) string {
+	if p.help.format == "json" {
+		return p.HelpJSON()
+	}
+	var b strings.Builder
+	p.generateHelp(&b, p.help.advanced, width)
+	return b.String()
+}
+
+// width returns the width the help text written to w is wrapped to, or 0 for
+// no wrapping. Unless set using SetHelpWidth(), only help text written to a
+// file, such as a terminal, is wrapped.
+func (p *ArgParser) width(w io.Writer) int {
+	if p.helpWidth != 0 {
+		return max(p.helpWidth, 0)
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return 0
+	}
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	return terminalWidth(f)
+}
+
+// wrapText wraps s to the given width, indenting all lines except the first
+// by indent spaces, which is assumed to be the column where s starts. Lines
+// in s are wrapped separately. If width is 0, s is only indented.
+func wrapText(width, indent int, s string) string {
+	pad := "\n" + strings.Repeat(" ", indent)
+	if width <= 0 || width-indent < 20 {
+		return strings.ReplaceAll(s, "\n", pad)
+	}
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		wrapped := ""
+		for _, word := range strings.Fields(line) {
+			if wrapped != "" && len(wrapped)+1+len(word) > width-indent {
+				lines = append(lines, wrapped)
+				wrapped = ""
+			}
+			if wrapped != "" {
+				wrapped += " "
+			}
+			wrapped += word
+		}
+		lines = append(lines, wrapped)
+	}
+	return strings.Join(lines, pad)
+}
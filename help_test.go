@@ -0,0 +1,486 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAddExample(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringPosVar(&a, "a", "usage-a")
+	p.AddExample("testprog x", "do x")
+	p.AddExample("testprog y", "")
+	expected := `usage: testprog [flag].. a
+
+positional arguments:
+  a   usage-a
+
+flags:
+  -h, --help   display this help text and exit
+
+examples:
+  testprog x
+      do x
+  testprog y
+`
+	if help := p.Help(); help != expected {
+		t.Fatalf("expected help:\n%s\ngot:\n%s", expected, help)
+	}
+}
+
+func TestAdvanced(t *testing.T) {
+	p := NewArgParser("testprog")
+	p.SetExitOnHelp(false)
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "", "usage-a")
+	var b int
+	p.IntVar(&b, "b-test", 0, "usage-b")
+	p.Advanced("b-test")
+	expected := `usage: testprog [flag]..
+
+flags:
+  -h, --help            display this help text and exit
+  -a, --a-test string   usage-a
+      --help-advanced   display this help text including advanced flags and exit
+`
+	if help := p.Help(); help != expected {
+		t.Fatalf("expected help:\n%s\ngot:\n%s", expected, help)
+	}
+	args := []string{"-hh"}
+	err := p.ParseArgs(args)
+	if !errors.Is(err, ErrHelp) {
+		t.Fatalf("expected ErrHelp, got: %v", err)
+	}
+	expected = `usage: testprog [flag]..
+
+flags:
+  -h, --help            display this help text and exit
+  -a, --a-test string   usage-a
+      --b-test int      usage-b
+      --help-advanced   display this help text including advanced flags and exit
+`
+	if help := p.RequestedHelp(); help != expected {
+		t.Fatalf("expected help:\n%s\ngot:\n%s", expected, help)
+	}
+}
+
+func TestAdvancedHelpFlag(t *testing.T) {
+	p := NewArgParser("testprog")
+	p.SetExitOnHelp(false)
+
+	var a int
+	p.IntVar(&a, "a-test", 0, "usage-a")
+	p.Advanced("a-test")
+	args := []string{"--help-advanced"}
+	err := p.ParseArgs(args)
+	if !errors.Is(err, ErrHelp) {
+		t.Fatalf("expected ErrHelp, got: %v", err)
+	}
+	if help := p.RequestedHelp(); help != p.HelpAdvanced() {
+		t.Fatalf("expected advanced help, got:\n%s", help)
+	}
+}
+
+func TestFlagPriority(t *testing.T) {
+	p := NewArgParser("testprog")
+	p.SortFlags = true
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "default-a", "usage-a")
+	var b string
+	p.StringVarP(&b, "b-test", "b", "default-b", "usage-b")
+	p.FlagPriority("b-test", 1)
+	p.FlagPriority("help", -1)
+	expected := `usage: testprog [flag]..
+
+flags:
+  -b, --b-test string   usage-b (default "default-b")
+  -a, --a-test string   usage-a (default "default-a")
+  -h, --help            display this help text and exit
+`
+	if help := p.Help(); help != expected {
+		t.Fatalf("expected help:\n%s\ngot:\n%s", expected, help)
+	}
+}
+
+func TestFlagSection(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "default-a", "usage-a")
+	var b string
+	p.StringVarP(&b, "b-test", "b", "default-b", "usage-b")
+	var c bool
+	p.BoolVarP(&c, "c-test", "c", false, "usage-c")
+	p.FlagSection("output options", "c-test", "a-test")
+	expected := `usage: testprog [flag]..
+
+flags:
+  -h, --help            display this help text and exit
+  -b, --b-test string   usage-b (default "default-b")
+
+output options:
+  -a, --a-test string   usage-a (default "default-a")
+  -c, --c-test          usage-c
+`
+	if help := p.Help(); help != expected {
+		t.Fatalf("expected help:\n%s\ngot:\n%s", expected, help)
+	}
+}
+
+func TestHelp(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "default-a", "usage-a")
+	var b string
+	p.StringPosVar(&b, "b", "usage-b")
+	var c []string
+	p.StringPosNVar(&c, "c", "usage-c", 0, -1)
+	expected := `usage: testprog [flag].. b [c]..
+
+positional arguments:
+  b   usage-b
+  c   usage-c
+
+flags:
+  -h, --help            display this help text and exit
+  -a, --a-test string   usage-a (default "default-a")
+`
+	if help := p.Help(); help != expected {
+		t.Fatalf("expected help:\n%s\ngot:\n%s", expected, help)
+	}
+}
+
+func TestHelpConstraintHints(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "json", "usage-a")
+	p.StringAllowOptions(&a, "a-test", []string{"json", "text"})
+	var b string
+	p.StringVarP(&b, "b-test", "b", "", "usage-b")
+	p.Required("b-test")
+	var c string
+	p.StringPosVar(&c, "c", "usage-c")
+	p.StringAllowRegexp(&c, "c", "^[a-z]+$")
+	p.StringDenyOptions(&c, "c", []string{"x", "y"})
+	var d float64
+	p.Float64Var(&d, "d-test", 0.5, "usage-d")
+	p.Float64AllowRangeExclusive(&d, "d-test", 0, 1)
+	var e float64
+	p.Float64Var(&e, "e-test", 1, "usage-e")
+	p.Float64AllowPositive(&e, "e-test")
+	p.Float64AllowRange(&e, "e-test", 0, 1.5)
+	var f int
+	p.IntVar(&f, "f-test", 0, "usage-f")
+	p.IntAllowNonNegative(&f, "f-test")
+	var g string
+	p.StringVar(&g, "g-test", "", "usage-g")
+	p.StringDenyRegexp(&g, "g-test", "^-")
+	expected := `usage: testprog [flag].. c
+
+positional arguments:
+  c   usage-c (matching "^[a-z]+$"; not one of: x, y)
+
+flags:
+  -h, --help            display this help text and exit
+  -a, --a-test string   usage-a (one of: json, text) (default "json")
+  -b, --b-test string   usage-b (required)
+      --d-test float    usage-d (0 to 1, exclusive) (default 0.5)
+      --e-test float    usage-e (0 to 1.5; positive) (default 1)
+      --f-test int      usage-f (non-negative)
+      --g-test string   usage-g (not matching "^-")
+`
+	if help := p.Help(); help != expected {
+		t.Fatalf("expected help:\n%s\ngot:\n%s", expected, help)
+	}
+}
+
+func TestHelpDescriptionEpilog(t *testing.T) {
+	p := NewArgParser("testprog")
+	p.Description = "testprog tests things."
+	p.Epilog = "See https://example.com for more."
+	expected := `usage: testprog [flag]..
+
+testprog tests things.
+
+flags:
+  -h, --help   display this help text and exit
+
+See https://example.com for more.
+`
+	if help := p.Help(); help != expected {
+		t.Fatalf("expected help:\n%s\ngot:\n%s", expected, help)
+	}
+}
+
+func TestHelpEnvAnnotation(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "default-a", "usage-a")
+	if err := p.SetAnnotation("a-test", EnvAnnotation, []string{"TESTPROG_A"}); err != nil {
+		t.Fatal(err)
+	}
+	expected := `usage: testprog [flag]..
+
+flags:
+  -h, --help            display this help text and exit
+  -a, --a-test string   usage-a [env: TESTPROG_A] (default "default-a")
+`
+	if help := p.Help(); help != expected {
+		t.Fatalf("expected help:\n%s\ngot:\n%s", expected, help)
+	}
+}
+
+func TestHelpJSON(t *testing.T) {
+	p := NewArgParser("testprog")
+	p.SetExitOnHelp(false)
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "json", "usage-a")
+	p.StringAllowOptions(&a, "a-test", []string{"json", "text"})
+	var b []string
+	p.StringPosNVar(&b, "b", "usage-b", 0, -1)
+	var c bool
+	p.BoolVar(&c, "c-test", false, "usage-c")
+	p.MarkHidden("c-test")
+	args := []string{"--help=json"}
+	err := p.ParseArgs(args)
+	if !errors.Is(err, ErrHelp) {
+		t.Fatalf("expected ErrHelp, got: %v", err)
+	}
+	var data HelpData
+	if err := json.Unmarshal([]byte(p.RequestedHelp()), &data); err != nil {
+		t.Fatalf("unexpected json error: %v", err)
+	}
+	if data.Name != "testprog" || data.Usage != "testprog [flag].. [b].." {
+		t.Fatalf("unexpected name or usage: %q, %q", data.Name, data.Usage)
+	}
+	if len(data.Positionals) != 1 || data.Positionals[0].Max != -1 {
+		t.Fatalf("unexpected positionals: %+v", data.Positionals)
+	}
+	if len(data.Flags) != 2 || data.Flags[1].Default != "json" ||
+		data.Flags[1].Constraints[0] != "one of: json, text" {
+		t.Fatalf("unexpected flags: %+v", data.Flags)
+	}
+
+	p = NewArgParser("testprog")
+	if help := p.HelpJSON(); !strings.Contains(help, `"positionals": [],`) {
+		t.Fatalf("expected empty positionals, got:\n%s", help)
+	}
+}
+
+func TestHelpNoExit(t *testing.T) {
+	p := NewArgParser("testprog")
+	p.SetExitOnHelp(false)
+
+	var a string
+	p.StringPosVar(&a, "a", "usage-a")
+	args := []string{"--help"}
+	err := p.ParseArgs(args)
+	if !errors.Is(err, ErrHelp) {
+		t.Fatalf("expected ErrHelp, got: %v", err)
+	}
+}
+
+func TestSetDefaultText(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "secret", "usage-a")
+	p.SetDefaultText("a-test", "")
+	var b int
+	p.IntVarP(&b, "b-test", "b", 0, "usage-b")
+	p.SetDefaultText("b-test", "auto")
+	var c int
+	p.IntVarP(&c, "c-test", "c", 5, "usage-c")
+	p.SetDefaultText("c-test", "")
+	expected := `usage: testprog [flag]..
+
+flags:
+  -h, --help            display this help text and exit
+  -a, --a-test string   usage-a
+  -b, --b-test int      usage-b (default auto)
+  -c, --c-test int      usage-c
+`
+	if help := p.Help(); help != expected {
+		t.Fatalf("expected help:\n%s\ngot:\n%s", expected, help)
+	}
+}
+
+func TestSetExit(t *testing.T) {
+	p := NewArgParser("testprog", ExitOnError)
+	code := -1
+	p.SetExit(func(c int) { code = c })
+	var out, errOut strings.Builder
+	p.SetOutput(&out)
+	p.SetErrOutput(&errOut)
+
+	var a string
+	p.StringVar(&a, "a-test", "", "usage-a")
+	p.Required("a-test")
+	args := []string{"--help"}
+	if err := p.ParseArgs(args); !errors.Is(err, ErrHelp) {
+		t.Fatalf("expected ErrHelp, got: %v", err)
+	}
+	if code != 0 || out.String() != p.Help() {
+		t.Fatalf("unexpected exit code %d and output:\n%s", code, out.String())
+	}
+
+	p = NewArgParser("testprog", ExitOnError)
+	p.SetExit(func(c int) { code = c })
+	p.SetErrOutput(&errOut)
+	p.StringVar(&a, "a-test", "", "usage-a")
+	p.Required("a-test")
+	args = []string{}
+	testError(t, p.ParseArgs(args), "missing required flag: a-test")
+	expected := `testprog: missing required flag: a-test
+usage: testprog [flag]..
+`
+	if code != 2 || errOut.String() != expected {
+		t.Fatalf("unexpected exit code %d and error output:\n%s", code, errOut.String())
+	}
+}
+
+func TestSetHelpTemplate(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "default-a", "usage-a")
+	p.Required("a-test")
+	var b string
+	p.StringPosVar(&b, "b", "usage-b")
+	p.SetHelpTemplate(
+		"{{.Usage}}\n{{range .Flags}}{{.Name}}={{.Default}}{{if .Required}}!{{end}} {{end}}\n" +
+			"{{join .Constraints \"; \"}}\n",
+	)
+	expected := "testprog [flag].. b\nhelp=false a-test=default-a! \na-test is required\n"
+	if help := p.Help(); help != expected {
+		t.Fatalf("expected help %q, got %q", expected, help)
+	}
+}
+
+func TestSetHelpWidth(t *testing.T) {
+	p := NewArgParser("testprog")
+	p.SetHelpWidth(40)
+	p.Description = "testprog tests things, and it does so using quite a few words."
+
+	var a string
+	p.StringPosVar(&a, "a", "usage-a is long enough to be wrapped into lines")
+	expected := `usage: testprog [flag].. a
+
+testprog tests things, and it does so
+using quite a few words.
+
+positional arguments:
+  a   usage-a is long enough to be
+      wrapped into lines
+
+flags:
+  -h, --help   display this help
+               text and exit
+`
+	if help := p.Help(); help != expected {
+		t.Fatalf("expected help:\n%s\ngot:\n%s", expected, help)
+	}
+
+	p.SetHelpWidth(0)
+	t.Setenv("COLUMNS", "40")
+	if help := p.Help(); strings.Count(help, "\n") != 9 {
+		t.Fatalf("expected unwrapped help, got:\n%s", help)
+	}
+	path := filepath.Join(t.TempDir(), "help")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	p.SetOutput(f)
+	p.SetExit(func(int) {})
+	if err := p.ParseArgs([]string{"--help"}); !errors.Is(err, ErrHelp) {
+		t.Fatalf("expected ErrHelp, got: %v", err)
+	}
+	if b, err := os.ReadFile(path); err != nil || string(b) != expected {
+		t.Fatalf("expected help written:\n%s\ngot:\n%s", expected, b)
+	}
+
+	p.SetHelpWidth(-1)
+	if err := f.Truncate(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.ParseArgs([]string{"--help"}); !errors.Is(err, ErrHelp) {
+		t.Fatalf("expected ErrHelp, got: %v", err)
+	}
+	if b, err := os.ReadFile(path); err != nil || strings.Count(string(b), "\n") != 9 {
+		t.Fatalf("expected unwrapped help written, got:\n%s", b)
+	}
+}
+
+func TestSetOutput(t *testing.T) {
+	p := NewArgParser("testprog", ExitOnError)
+	p.SetExit(func(int) {})
+	p.SetErrorUsage(ErrorUsageNone)
+	var out, errOut strings.Builder
+	p.SetOutput(&out)
+	p.SetErrOutput(&errOut)
+
+	var a string
+	p.StringVar(&a, "a-test", "", "usage-a")
+	if err := p.MarkDeprecated("a-test", "use b-test"); err != nil {
+		t.Fatal(err)
+	}
+	var b int
+	p.IntVar(&b, "b-test", 0, "usage-b")
+	args := []string{"--a-test", "x", "--b-test", "y"}
+	testError(
+		t, p.ParseArgs(args),
+		`invalid argument "y" for "--b-test" flag: strconv.ParseInt: parsing "y": invalid syntax`,
+	)
+	expected := `Flag --a-test has been deprecated, use b-test
+testprog: invalid argument "y" for "--b-test" flag: strconv.ParseInt: parsing "y": invalid syntax
+`
+	if errOut.String() != expected {
+		t.Fatalf("expected error output:\n%s\ngot:\n%s", expected, errOut.String())
+	}
+
+	p = NewArgParser("testprog")
+	p.SetExit(func(int) {})
+	p.SetOutput(&out)
+	args = []string{"--help"}
+	if err := p.ParseArgs(args); !errors.Is(err, ErrHelp) {
+		t.Fatalf("expected ErrHelp, got: %v", err)
+	}
+	if out.String() != p.Help() {
+		t.Fatalf("expected output:\n%s\ngot:\n%s", p.Help(), out.String())
+	}
+}
+
+func TestSetUsage(t *testing.T) {
+	p := NewArgParser("testprog")
+	p.SetUsage("testprog [flag].. file", "testprog --list")
+	expected := `usage: testprog [flag].. file
+   or: testprog --list
+
+flags:
+  -h, --help   display this help text and exit
+`
+	if help := p.Help(); help != expected {
+		t.Fatalf("expected help:\n%s\ngot:\n%s", expected, help)
+	}
+}
@@ -0,0 +1,58 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseINI parses the INI format described by ConfigINI into values keyed by
+// the section and key joined using ".".
+func parseINI(data []byte) (map[string][]string, error) {
+	values := map[string][]string{}
+	prefix := ""
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: unterminated section header: %s", i+1, line)
+			}
+			section, sub, ok := strings.Cut(line[1:len(line)-1], " ")
+			section = strings.TrimSpace(section)
+			if ok {
+				sub = strings.TrimSpace(sub)
+				if len(sub) < 2 || sub[0] != '"' || sub[len(sub)-1] != '"' {
+					return nil, fmt.Errorf("line %d: invalid section header: %s", i+1, line)
+				}
+				section = section + "." + sub[1:len(sub)-1]
+			}
+			if section == "" {
+				return nil, fmt.Errorf("line %d: empty section name", i+1)
+			}
+			prefix = section + "."
+			continue
+		}
+
+		sep := strings.IndexAny(line, "=:")
+		if sep == -1 {
+			return nil, fmt.Errorf("line %d: expected key and value separated by \"=\"", i+1)
+		}
+		key := strings.TrimSpace(line[:sep])
+		if key == "" {
+			return nil, fmt.Errorf("line %d: empty key", i+1)
+		}
+		v := strings.TrimSpace(line[sep+1:])
+		if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+			v = v[1 : len(v)-1]
+		}
+		values[prefix+key] = append(values[prefix+key], v)
+	}
+	return values, nil
+}
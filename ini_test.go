@@ -0,0 +1,53 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestParseINIFail(t *testing.T) {
+	for _, data := range []string{
+		"a",
+		"= 1",
+		"[a",
+		"[]",
+		"[a b]",
+	} {
+		if _, err := parseINI([]byte(data)); err == nil {
+			t.Fatalf("expected error for %q", data)
+		}
+	}
+}
+
+func TestParseINIOK(t *testing.T) {
+	data := `; comment
+# comment
+a = 1
+b: "x ; y"
+
+[c]
+d = 'e'
+f = 1
+f = 2
+
+[c "g"]
+h =
+`
+	values, err := parseINI([]byte(data))
+	testNoError(t, err)
+	expected := map[string][]string{
+		"a":     {"1"},
+		"b":     {"x ; y"},
+		"c.d":   {"e"},
+		"c.f":   {"1", "2"},
+		"c.g.h": {""},
+	}
+	if !maps.EqualFunc(values, expected, slices.Equal) {
+		t.Fatalf("expected %q, got %q", expected, values)
+	}
+}
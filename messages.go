@@ -0,0 +1,213 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+)
+
+// Messages is a catalog of user-facing messages, mapping message IDs to
+// fmt format strings. See DefaultMessages for the message IDs, and the
+// arguments each message is formatted with. Arguments can be reordered or
+// omitted using explicit argument indexes, e.g. "%[2]s must be set for %[1]s".
+type Messages map[string]string
+
+// DefaultMessages is the default, English, message catalog. Messages missing
+// from a catalog set using SetMessages() fall back to these.
+var DefaultMessages = Messages{
+	// Help text labels.
+	"help.examples":      "examples:",
+	"help.flag":          "display this help text and exit",
+	"help.flag-advanced": "display this help text including advanced flags and exit",
+	"help.flags":         "flags:",
+	"help.positionals":   "positional arguments:",
+	"help.usage":         "usage:",
+	"help.usage-or":      "or:",
+
+	// Sample config header, see GenSampleConfig().
+	"config.sample": "sample configuration for %s",
+
+	// Constraint hints, appended to flag and positional argument usage.
+	"hint.denied":                "not one of: %s",
+	"hint.denied-regexp":         "not matching %q",
+	"hint.env":                   "env: %s",
+	"hint.non-negative":          "non-negative",
+	"hint.options":               "one of: %s",
+	"hint.positive":              "positive",
+	"hint.range":                 "%d to %d",
+	"hint.range-float":           "%g to %g",
+	"hint.range-float-exclusive": "%g to %g, exclusive",
+	"hint.regexp":                "matching %q",
+	"hint.required":              "required",
+
+	// Constraint descriptions, see HelpData.
+	"constraint.at-least-one":          "at least one of %s is required",
+	"constraint.denied":                "%s must not be one of: %s",
+	"constraint.denied-regexp":         "%s must not match regexp %q",
+	"constraint.depends-on":            "%s requires %s",
+	"constraint.exactly-one":           "exactly one of %s is required",
+	"constraint.mutually-exclusive":    "%s are mutually exclusive",
+	"constraint.non-negative":          "%s must be non-negative",
+	"constraint.options":               "%s must be one of: %s",
+	"constraint.positive":              "%s must be positive",
+	"constraint.range":                 "%s must be within range %d to %d",
+	"constraint.range-float":           "%s must be within range %g to %g",
+	"constraint.range-float-exclusive": "%s must be within range %g to %g, exclusive",
+	"constraint.regexp":                "%s must match regexp %q",
+	"constraint.required":              "%s is required",
+	"constraint.required-if":           "%s is required when %s is %q",
+	"constraint.required-if-set":       "%s is required when %s is set",
+	"constraint.required-together":     "%s are required together",
+
+	// Parse errors.
+	"error.at-least-one":            "at least one of the flags %s is required",
+	"error.config":                  "config file %s: %v",
+	"error.config-key":              "config file %s: unknown key: %s",
+	"error.depends-on":              "flag %s cannot be used without flag %s",
+	"error.dotenv":                  "dotenv file %s: %v",
+	"error.env":                     "environment variable %s: %v",
+	"error.exactly-one":             "exactly one of the flags %s is required",
+	"error.exactly-one-multiple":    "%s and %s are mutually exclusive flags, exactly one of %s is required",
+	"error.multiple-values":         "flag %s does not accept multiple values",
+	"error.mutually-exclusive":      "%s and %s are mutually exclusive flags",
+	"error.positionals-few":         "insufficient number of positional arguments, see --help",
+	"error.positionals-none":        "no positional arguments expected",
+	"error.posn-max":                "got %d %q positional argument(s), expected %d at most, see --help",
+	"error.posn-min":                "got %d %q positional argument(s), expected %d at least, see --help",
+	"error.posn-missing":            "no %q positional argument(s) provided, see --help",
+	"error.posn-missing-min":        "no %q positional argument(s) provided, expected %d, see --help",
+	"error.required":                "missing required flag: %s",
+	"error.required-if":             "missing required flag: %s, required when %s is %q",
+	"error.required-if-set":         "missing required flag: %s, required when %s is set",
+	"error.required-multiple":       "missing required flags: %s",
+	"error.required-together":       "flags %s are required together, missing: %s",
+	"error.response-file":           "response file %s: %v",
+	"error.response-file-recursive": "response file %s: recursive inclusion",
+	"error.source":                  "value source: %v",
+
+	// Invalid value errors.
+	"value.denied":          "invalid value: %q is a denied value",
+	"value.denied-regexp":   "invalid value: %q is matching denied regexp %q",
+	"value.dir-is-file":     "invalid value: %q is a file, not a directory",
+	"value.invalid":         "invalid value: %v",
+	"value.non-negative":    "invalid value: %v is not a non-negative number",
+	"value.not-absolute":    "invalid value: %q is not an absolute URL",
+	"value.not-allowed":     "invalid value: %q is not allowed",
+	"value.not-creatable":   "invalid value: %q cannot be created, parent directory %q does not exist",
+	"value.not-dir":         "invalid value: %q is not a directory",
+	"value.not-exist":       "invalid value: %q does not exist",
+	"value.not-file":        "invalid value: %q is not a regular file",
+	"value.not-host":        "invalid value: %q has invalid host %q",
+	"value.not-host-port":   "invalid value: %q is not a host and port pair",
+	"value.not-hostname":    "invalid value: %q is not a valid hostname",
+	"value.not-port":        "invalid value: %q has invalid port %q",
+	"value.not-port-number": "invalid value: %q is not a port number",
+	"value.not-readable":    "invalid value: %q is not readable",
+	"value.not-url":         "invalid value: %q is not a valid URL: %v",
+	"value.not-uuid":        "invalid value: %q is not a UUID",
+	"value.not-writable":    "invalid value: %q is not writable",
+	"value.options":         "invalid value: %q is not among options: %q",
+	"value.options-suggest": "invalid value: %q is not among options: %q, did you mean %q?",
+	"value.or":              "%v, or %v",
+	"value.parent-writable": "invalid value: %q cannot be created, parent directory %q is not writable",
+	"value.port":            "invalid value: %d is not a valid port number, expected %d to %d",
+	"value.port-or-zero":    "invalid value: %d is not a valid port number, expected 0 or %d to %d",
+	"value.positive":        "invalid value: %v is not a positive number",
+	"value.range":           "invalid value: %v is not within range %v to %v",
+	"value.range-exclusive": "invalid value: %v is not within range %v to %v, exclusive",
+	"value.regexp":          "invalid value: %q is not matching regexp %q",
+	"value.semver":          "invalid value: %q is not matching version constraint %q",
+	"value.time":            "invalid value: %q is not matching any time format: %q",
+	"value.url-scheme":      "invalid value: %q has URL scheme %q, which is not among: %q",
+	"value.uuid-variant":    "invalid value: %q is not an RFC 4122 variant UUID",
+	"value.uuid-version":    "invalid value: %q is a version %d UUID, expected version: %v",
+}
+
+// messageError is an error formatted from a message catalog when its message
+// is requested, allowing errors returned by validators to be localized using
+// the catalog of the parser enforcing them.
+type messageError struct {
+	id       string
+	args     []any
+	messages Messages
+}
+
+// SetMessages sets the message catalog used for errors and help text, e.g. to
+// localize them. Messages missing from the catalog fall back to
+// DefaultMessages.
+func (p *ArgParser) SetMessages(messages Messages) {
+	p.messages = messages
+	if flag := p.Lookup("help"); flag != nil {
+		flag.Usage = p.message("help.flag")
+	}
+	if flag := p.Lookup("help-advanced"); flag != nil && len(p.advanced) > 0 {
+		flag.Usage = p.message("help.flag-advanced")
+	}
+}
+
+// errorf returns an error with the given message from the parser's catalog.
+func (p *ArgParser) errorf(id string, args ...any) error {
+	return &messageError{id, args, p.messages}
+}
+
+// localize makes an error returned by a validator use the parser's catalog.
+func (p *ArgParser) localize(err error) error {
+	var e *messageError
+	if errors.As(err, &e) {
+		e.localize(p.messages)
+	}
+	return err
+}
+
+// message returns the given message from the parser's catalog.
+func (p *ArgParser) message(id string, args ...any) string {
+	return p.messages.format(id, args...)
+}
+
+// messagesAll returns the parser's catalog merged into DefaultMessages.
+func (p *ArgParser) messagesAll() Messages {
+	all := maps.Clone(DefaultMessages)
+	maps.Copy(all, p.messages)
+	return all
+}
+
+func (e *messageError) Error() string {
+	return e.messages.format(e.id, e.args...)
+}
+
+func (e *messageError) Unwrap() []error {
+	var errs []error
+	for _, arg := range e.args {
+		if err, ok := arg.(error); ok {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+func (e *messageError) localize(messages Messages) {
+	e.messages = messages
+	for _, arg := range e.args {
+		if err, ok := arg.(*messageError); ok {
+			err.localize(messages)
+		}
+	}
+}
+
+func (m Messages) format(id string, args ...any) string {
+	format, ok := m[id]
+	if !ok {
+		format = DefaultMessages[id]
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// valueError returns an error with the given message from DefaultMessages, to
+// be localized by the parser enforcing the validator returning it.
+func valueError(id string, args ...any) error {
+	return &messageError{id: id, args: args}
+}
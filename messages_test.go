@@ -0,0 +1,79 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+func TestSetMessages(t *testing.T) {
+	p := NewArgParser("testprog")
+	p.SetMessages(Messages{
+		"error.required": "flagga saknas: %s",
+		"help.flag":      "visa hjälp",
+		"help.flags":     "flaggor:",
+		"help.usage":     "användning:",
+		"hint.required":  "krävs",
+	})
+
+	var a string
+	p.StringVar(&a, "a-test", "", "usage-a")
+	p.Required("a-test")
+	expected := `användning: testprog [flag]..
+
+flaggor:
+  -h, --help            visa hjälp
+      --a-test string   usage-a (krävs)
+`
+	if help := p.Help(); help != expected {
+		t.Fatalf("expected help:\n%s\ngot:\n%s", expected, help)
+	}
+	testError(t, p.ParseArgs([]string{}), "flagga saknas: a-test")
+}
+
+func TestSetMessagesValidator(t *testing.T) {
+	p := NewArgParser("testprog")
+	p.SetMessages(Messages{
+		"value.not-exist": "ogiltigt värde: %q finns inte",
+		"value.or":        "%v, eller %v",
+	})
+
+	var a string
+	p.StringVar(&a, "a-test", "", "usage-a")
+	p.Attach("a-test", Or(AllowExistingFile(), AllowExistingDir()))
+	testError(
+		t, p.ParseArgs([]string{"--a-test=/nonexistent"}),
+		`a-test: ogiltigt värde: "/nonexistent" finns inte, `+
+			`eller ogiltigt värde: "/nonexistent" finns inte`,
+	)
+
+	err := AllowReadable()("/nonexistent")
+	testError(t, err, `invalid value: "/nonexistent" does not exist`)
+
+	err = AllowReadable()("/nonexistent/\x00")
+	var pathErr *fs.PathError
+	if !errors.As(err, &pathErr) {
+		t.Fatalf("expected error wrapping *fs.PathError, got: %v", err)
+	}
+}
+
+func TestSetMessagesReorder(t *testing.T) {
+	p := NewArgParser("testprog")
+	p.SetMessages(Messages{"error.required-if": "%[2]s=%[3]q kräver flaggan %[1]s"})
+
+	var a, b, c string
+	p.StringVar(&a, "a-test", "", "usage-a")
+	p.StringVar(&b, "b-test", "", "usage-b")
+	p.RequiredIf("a-test", "b-test", "y")
+	testError(t, p.ParseArgs([]string{"--b-test=y"}), `b-test="y" kräver flaggan a-test`)
+
+	p = NewArgParser("testprog")
+	p.SetMessages(Messages{"value.options": "ogiltigt värde: %[1]q"})
+	p.StringVar(&c, "c-test", "x", "usage-c")
+	p.StringAllowOptions(&c, "c-test", []string{"x"})
+	testError(t, p.ParseArgs([]string{"--c-test=z"}), `c-test: ogiltigt värde: "z"`)
+}
@@ -0,0 +1,161 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// cliArg is a flag value given on the command line.
+type cliArg struct {
+	name  string
+	value string
+}
+
+// argparsePkgPath and pflagPkgPath are the packages of the flag values that
+// Reload() resets without them implementing Resetter.
+var (
+	argparsePkgPath = reflect.TypeOf(ArgParser{}).PkgPath()
+	pflagPkgPath    = reflect.TypeOf(pflag.FlagSet{}).PkgPath()
+)
+
+// Resetter is implemented by custom flag values that can be reset to their
+// default value by Reload(), see Reload().
+type Resetter interface {
+	Reset() error
+}
+
+type onChange struct {
+	name string
+	fn   func(oldValue, newValue string)
+}
+
+// OnChange adds a function that is called by Reload() when the value of the
+// given flag changes, with the old and new value formatted as by
+// pflag.Value.String().
+func (p *ArgParser) OnChange(name string, fn func(oldValue, newValue string)) {
+	if flag := p.Lookup(name); flag == nil {
+		p.die("on change: undefined flag: %s", name)
+	}
+	if fn == nil {
+		p.die("on change: %s: cannot be defined with nil func", name)
+	}
+	p.onChanges = append(p.onChanges, onChange{name, fn})
+}
+
+// Reload re-resolves the flag values after ParseArgs(), such as when a
+// long-running program receives SIGHUP. The flags are reset to their default
+// values, and the values given on the command line are set again, while
+// environment variables, dotenv files, config files and custom sources are
+// read again, after which all checks are enforced as by ParseArgs(). The
+// functions added using OnChange() are then called for the flags whose values
+// changed.
+//
+// Custom flag values, i.e. not defined by pflag, are only reset if they
+// implement Resetter, and are otherwise left as is before being set again.
+//
+// If an error is returned, the values may be partially reloaded, and no
+// functions are called.
+func (p *ArgParser) Reload() error {
+	if !p.Parsed() {
+		p.die("reload: cannot reload pre-parse")
+	}
+	oldValues := map[string]string{}
+	var err error
+	p.VisitAll(func(flag *pflag.Flag) {
+		oldValues[flag.Name] = flag.Value.String()
+		if err == nil {
+			err = p.resetValue(flag)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	for _, arg := range p.cliArgs {
+		if err := p.Set(arg.name, arg.value); err != nil {
+			return err
+		}
+	}
+	if err := p.parseValues(); err != nil {
+		return err
+	}
+	for _, c := range p.onChanges {
+		newValue := p.Lookup(c.name).Value.String()
+		if newValue != oldValues[c.name] {
+			c.fn(oldValues[c.name], newValue)
+		}
+	}
+	return nil
+}
+
+// resetValue resets the given flag to its default value, or to an empty value
+// for slice flags given on the command line, which are then appended to.
+func (p *ArgParser) resetValue(flag *pflag.Flag) error {
+	defer func() { flag.Changed = false }()
+	if r, ok := flag.Value.(Resetter); ok {
+		return r.Reset()
+	}
+	t := reflect.TypeOf(flag.Value)
+	if t.Kind() != reflect.Pointer ||
+		(t.Elem().PkgPath() != pflagPkgPath && t.Elem().PkgPath() != argparsePkgPath) {
+		return nil
+	}
+	if sv, ok := flag.Value.(pflag.SliceValue); ok {
+		values := []string{}
+		if !p.cliGiven(flag.Name) {
+			values = splitDefValue(flag.DefValue)
+		}
+		return sv.Replace(values)
+	}
+	switch flag.Value.Type() {
+	case "stringToInt", "stringToInt64", "stringToString":
+		return resetMapValue(flag)
+	}
+	if flag.Value.String() == flag.DefValue {
+		return nil
+	}
+	if flag.DefValue == "<nil>" {
+		// A nil IP, IPMask or IPNet, which Set() does not accept.
+		reflect.ValueOf(flag.Value).Elem().SetZero()
+		return nil
+	}
+	return flag.Value.Set(flag.DefValue)
+}
+
+// resetMapValue resets a pflag map flag to its default value. Once set, the
+// value's Set() merges into the map rather than replacing it, so its
+// unexported map and changed fields are reset first.
+func resetMapValue(flag *pflag.Flag) error {
+	v := reflect.ValueOf(flag.Value).Elem()
+	m, changed := v.FieldByName("value"), v.FieldByName("changed")
+	if m.Kind() != reflect.Pointer || changed.Kind() != reflect.Bool {
+		return flag.Value.Set(strings.Trim(flag.DefValue, "[]"))
+	}
+	reflect.NewAt(m.Type().Elem(), m.UnsafePointer()).Elem().SetZero()
+	unchange := func() {
+		reflect.NewAt(changed.Type(), changed.Addr().UnsafePointer()).Elem().SetBool(false)
+	}
+	unchange()
+	if def := strings.Trim(flag.DefValue, "[]"); def != "" {
+		if err := flag.Value.Set(def); err != nil {
+			return err
+		}
+		unchange()
+	}
+	return nil
+}
+
+// cliGiven returns whether the given flag is given on the command line.
+func (p *ArgParser) cliGiven(name string) bool {
+	for _, arg := range p.cliArgs {
+		if arg.name == name {
+			return true
+		}
+	}
+	return false
+}
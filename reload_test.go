@@ -0,0 +1,159 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"maps"
+	"net"
+	"os"
+	"slices"
+	"testing"
+)
+
+func TestReloadFail(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a int
+	p.IntVar(&a, "a-test", 0, "usage-a")
+	p.IntAllowRange(&a, "a-test", 0, 10)
+	path := writeConfig(t, "config.yaml", "a-test: 1\n")
+	p.ConfigFile(path, ConfigYAML)
+	called := false
+	p.OnChange("a-test", func(oldValue, newValue string) { called = true })
+	testNoError(t, p.ParseArgs([]string{}))
+
+	if err := os.WriteFile(path, []byte("a-test: 11\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testError(t, p.Reload(), "a-test: invalid value: 11 is not within range 0 to 10")
+	if called {
+		t.Fatalf("unexpected call of on change func")
+	}
+}
+
+func TestReloadOK(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVar(&a, "a-test", "default-a", "usage-a")
+	var b []string
+	p.StringSliceVar(&b, "b-test", []string{"default-b"}, "usage-b")
+	var c []string
+	p.StringSliceVar(&c, "c-test", nil, "usage-c")
+	var d string
+	p.StringVar(&d, "d-test", "default-d", "usage-d")
+	p.BindEnv("d-test", "TESTPROG_D")
+	path := writeConfig(t, "config.yaml", "a-test: config-a\nb-test: [config-b]\nc-test: [x]\n")
+	p.ConfigFile(path, ConfigYAML)
+	var changes []string
+	for _, name := range []string{"a-test", "b-test", "c-test", "d-test"} {
+		p.OnChange(name, func(oldValue, newValue string) {
+			changes = append(changes, name+": "+oldValue+" -> "+newValue)
+		})
+	}
+	t.Setenv("TESTPROG_D", "env-d")
+	testNoError(t, p.ParseArgs([]string{"--c-test", "cli-c1", "--c-test", "cli-c2"}))
+	if a != "config-a" || !slices.Equal(b, []string{"config-b"}) ||
+		!slices.Equal(c, []string{"cli-c1", "cli-c2"}) || d != "env-d" {
+		t.Fatalf("unexpected values: %q, %q, %q, %q", a, b, c, d)
+	}
+
+	content := "b-test: [config-b1, config-b2]\nc-test: [y]\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	os.Unsetenv("TESTPROG_D")
+	testNoError(t, p.Reload())
+	if a != "default-a" || !slices.Equal(b, []string{"config-b1", "config-b2"}) ||
+		!slices.Equal(c, []string{"cli-c1", "cli-c2"}) || d != "default-d" {
+		t.Fatalf("unexpected values: %q, %q, %q, %q", a, b, c, d)
+	}
+	expected := []string{
+		"a-test: config-a -> default-a",
+		"b-test: [config-b] -> [config-b1,config-b2]",
+		"d-test: env-d -> default-d",
+	}
+	if !slices.Equal(changes, expected) {
+		t.Fatalf("expected changes %q, got %q", expected, changes)
+	}
+	if source := p.Source("a-test"); source != SourceDefault {
+		t.Fatalf("unexpected source: %v", source)
+	}
+	if source := p.Source("c-test"); source != SourceFlag {
+		t.Fatalf("unexpected source: %v", source)
+	}
+}
+
+func TestReloadValueTypes(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a map[string]string
+	p.StringToStringVar(&a, "a-test", map[string]string{"env": "prod"}, "usage-a")
+	var b map[string]string
+	p.StringToStringVar(&b, "b-test", map[string]string{"env": "prod"}, "usage-b")
+	p.BindEnv("b-test", "TESTPROG_B")
+	var c net.IPNet
+	p.IPNetVar(&c, "c-test", net.IPNet{}, "usage-c")
+	var d net.IP
+	p.IPVar(&d, "d-test", nil, "usage-d")
+	p.BindEnv("d-test", "TESTPROG_D")
+	t.Setenv("TESTPROG_B", "team=y")
+	t.Setenv("TESTPROG_D", "10.0.0.1")
+	testNoError(t, p.ParseArgs([]string{"--a-test", "team=x", "--c-test", "10.0.0.0/8"}))
+
+	os.Unsetenv("TESTPROG_B")
+	os.Unsetenv("TESTPROG_D")
+	for range 2 {
+		testNoError(t, p.Reload())
+		if !maps.Equal(a, map[string]string{"team": "x"}) ||
+			!maps.Equal(b, map[string]string{"env": "prod"}) ||
+			c.String() != "10.0.0.0/8" || d != nil {
+			t.Fatalf("unexpected values: %q, %q, %v, %v", a, b, c, d)
+		}
+	}
+}
+
+// appConfigValue is a custom value setting a field of a larger struct.
+type appConfigValue struct {
+	cfg *appConfig
+}
+
+type appConfig struct {
+	Level   string
+	Runtime string
+}
+
+func (v appConfigValue) Set(s string) error { v.cfg.Level = s; return nil }
+func (v appConfigValue) String() string     { return v.cfg.Level }
+func (v appConfigValue) Type() string       { return "string" }
+
+// resettableValue is a custom value implementing Resetter.
+type resettableValue struct {
+	value string
+}
+
+func (v *resettableValue) Reset() error       { v.value = "default"; return nil }
+func (v *resettableValue) Set(s string) error { v.value = s; return nil }
+func (v *resettableValue) String() string     { return v.value }
+func (v *resettableValue) Type() string       { return "string" }
+
+func TestReloadCustomValues(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	cfg := appConfig{Level: "info"}
+	p.Var(appConfigValue{&cfg}, "a-test", "usage-a")
+	b := resettableValue{"default"}
+	p.Var(&b, "b-test", "usage-b")
+	p.BindEnv("b-test", "TESTPROG_B")
+	t.Setenv("TESTPROG_B", "env-b")
+	testNoError(t, p.ParseArgs([]string{"--a-test", "debug"}))
+
+	cfg.Runtime = "running"
+	os.Unsetenv("TESTPROG_B")
+	testNoError(t, p.Reload())
+	if cfg != (appConfig{"debug", "running"}) || b.value != "default" {
+		t.Fatalf("unexpected values: %+v, %q", cfg, b.value)
+	}
+}
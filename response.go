@@ -0,0 +1,143 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// SetResponseFiles sets whether arguments of the form @file are expanded by
+// ParseArgs() to the arguments read from the file, which may in turn contain
+// @file arguments. Arguments following "--" are not expanded, nor are flag
+// values given as separate arguments, e.g. "@alice" in "--user @alice".
+//
+// Arguments in the file are separated by whitespace, which can be included
+// by quoting. Single quotes preserve all characters literally, while in
+// double quotes and unquoted, a backslash escapes the following character.
+// A "#" starting an argument starts a comment, lasting to the end of the line.
+func (p *ArgParser) SetResponseFiles(enabled bool) {
+	p.responseFiles = enabled
+}
+
+// expandResponseFiles expands the @file arguments, where files are the files
+// currently being expanded, for detecting recursion.
+func (p *ArgParser) expandResponseFiles(args []string, files []string) ([]string, error) {
+	var expanded []string
+	for i, arg := range args {
+		if arg == "--" {
+			return append(expanded, args[i:]...), nil
+		}
+		path, ok := strings.CutPrefix(arg, "@")
+		if !ok || path == "" || (i > 0 && p.flagTakesValue(args[i-1])) {
+			expanded = append(expanded, arg)
+			continue
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, p.errorf("error.response-file", path, err)
+		}
+		if slices.Contains(files, abs) {
+			return nil, p.errorf("error.response-file-recursive", path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, p.errorf("error.response-file", path, err)
+		}
+		fileArgs, err := splitResponseFile(string(data))
+		if err != nil {
+			return nil, p.errorf("error.response-file", path, err)
+		}
+		fileArgs, err = p.expandResponseFiles(fileArgs, append(files, abs))
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, fileArgs...)
+	}
+	return expanded, nil
+}
+
+// flagTakesValue returns whether arg is a flag whose value is given as the
+// following argument, e.g. "--name" or "-n" of a non-boolean flag.
+func (p *ArgParser) flagTakesValue(arg string) bool {
+	if name, ok := strings.CutPrefix(arg, "--"); ok {
+		flag := p.Lookup(name)
+		return name != "" && flag != nil && flag.NoOptDefVal == ""
+	}
+	shorthands, ok := strings.CutPrefix(arg, "-")
+	if !ok {
+		return false
+	}
+	for i := 0; i < len(shorthands); i++ {
+		flag := p.ShorthandLookup(shorthands[i : i+1])
+		if flag == nil {
+			return false
+		}
+		if flag.NoOptDefVal == "" {
+			return i == len(shorthands)-1
+		}
+	}
+	return false
+}
+
+// splitResponseFile splits the content of a response file into arguments.
+func splitResponseFile(s string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+	quote := byte(0)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				arg.WriteByte(c)
+			}
+		case c == '\\':
+			i++
+			if i == len(s) {
+				return nil, fmt.Errorf("trailing backslash")
+			}
+			if s[i] != '\n' {
+				arg.WriteByte(s[i])
+			}
+			inArg = true
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			} else {
+				arg.WriteByte(c)
+			}
+		case c == '"' || c == '\'':
+			quote = c
+			inArg = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		case c == '#' && !inArg:
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+		default:
+			arg.WriteByte(c)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
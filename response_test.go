@@ -0,0 +1,69 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSetResponseFilesFail(t *testing.T) {
+	p := NewArgParser("testprog")
+	p.SetResponseFiles(true)
+
+	var a string
+	p.StringVar(&a, "a-test", "", "usage-a")
+	path := filepath.Join(t.TempDir(), "nonexistent")
+	testError(
+		t, p.ParseArgs([]string{"@" + path}),
+		"response file "+path+": open "+path+": no such file or directory",
+	)
+
+	path = filepath.Join(t.TempDir(), "args")
+	inner := writeConfig(t, "inner", "--a-test=x @"+path)
+	if err := os.WriteFile(path, []byte("@"+inner), 0o600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	testError(t, p.ParseArgs([]string{"@" + path}), "response file "+path+": recursive inclusion")
+
+	path = writeConfig(t, "args", "--a-test='x")
+	testError(t, p.ParseArgs([]string{"@" + path}), "response file "+path+": unterminated quote")
+}
+
+func TestSetResponseFilesOK(t *testing.T) {
+	p := NewArgParser("testprog")
+	p.SetResponseFiles(true)
+
+	var a string
+	p.StringVar(&a, "a-test", "", "usage-a")
+	var b []string
+	p.StringPosNVar(&b, "b", "usage-b", 0, -1)
+	inner := writeConfig(t, "inner", "# comment\n'b 1' \"b\\\"2\" b\\ 3\n")
+	outer := writeConfig(t, "outer", "--a-test x\n@"+inner+"\n")
+	args := []string{"@" + outer, "b4", "--", "@b5"}
+	testNoError(t, p.ParseArgs(args))
+	if a != "x" || !slices.Equal(b, []string{"b 1", `b"2`, "b 3", "b4", "--", "@b5"}) {
+		t.Fatalf("unexpected values: %q, %q", a, b)
+	}
+
+	p = NewArgParser("testprog")
+	p.SetResponseFiles(true)
+	p.StringVarP(&a, "a-test", "a", "", "usage-a")
+	var c bool
+	p.BoolVarP(&c, "c-test", "c", false, "usage-c")
+	p.StringPosNVar(&b, "b", "usage-b", 0, -1)
+	path := writeConfig(t, "args", "b1")
+	for _, args := range [][]string{
+		{"--a-test", "@alice", "--c-test", "@" + path},
+		{"-ca", "@alice", "-c", "@" + path},
+	} {
+		testNoError(t, p.ParseArgs(args))
+		if a != "@alice" || !slices.Equal(b, []string{"b1"}) {
+			t.Fatalf("unexpected values: %q, %q", a, b)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// sampleNode is a key in a sample config, with either an entry or children.
+type sampleNode struct {
+	name     string
+	comment  string
+	values   []string
+	disabled bool // written commented out, e.g. for a hidden default
+	children []*sampleNode
+}
+
+func (n *sampleNode) child(name string) *sampleNode {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	c := &sampleNode{name: name}
+	n.children = append(n.children, c)
+	return c
+}
+
+// enabled returns whether n is an entry not commented out, or has any.
+func (n *sampleNode) enabled() bool {
+	if n.children == nil {
+		return !n.disabled && len(n.values) > 0
+	}
+	return slices.ContainsFunc(n.children, (*sampleNode).enabled)
+}
+
+// GenSampleConfig writes a sample config file in the given format, with an
+// entry for each flag not hidden, keyed as described by ConfigFile(). The
+// entries are set to the flags' default values, and preceded by comments of
+// the flags' usage and constraints, as displayed in the help text. The entries
+// of flags with a default text, see SetDefaultText(), are commented out and
+// show the text instead, if any.
+func (p *ArgParser) GenSampleConfig(w io.Writer, format ConfigFormat) error {
+	if format != ConfigYAML && format != ConfigTOML && format != ConfigINI {
+		p.die("gen sample config: unknown format: %d", format)
+	}
+	root := &sampleNode{}
+	p.VisitAll(func(flag *pflag.Flag) {
+		if flag.Name == "help" || flag.Name == "help-advanced" || flag.Hidden {
+			return
+		}
+		key := p.configKey(flag.Name)
+		node := root
+		switch format {
+		case ConfigYAML:
+			for _, part := range strings.Split(key, ".") {
+				node = node.child(part)
+			}
+		case ConfigTOML:
+			node = node.child(key)
+		case ConfigINI:
+			if section, name, ok := strings.Cut(key, "."); ok {
+				node = node.child(section).child(name)
+			} else {
+				node = node.child(key)
+			}
+		}
+		node.comment = flag.Usage
+		if hints := p.constraintHints(flag.Name); len(hints) > 0 {
+			node.comment = fmt.Sprintf("%s (%s)", node.comment, strings.Join(hints, "; "))
+		}
+		if text, ok := p.defaultTexts[flag.Name]; ok {
+			node.disabled = true
+			if text != "" {
+				node.values = []string{text}
+			}
+		} else {
+			node.values = sampleValues(flag, format)
+		}
+	})
+
+	var b strings.Builder
+	switch format {
+	case ConfigYAML:
+		writeSampleComment(&b, "#", 0, p.message("config.sample", p.Name))
+		writeSampleYAML(&b, root.children, 0)
+	case ConfigTOML:
+		writeSampleComment(&b, "#", 0, p.message("config.sample", p.Name))
+		for _, n := range root.children {
+			b.WriteString("\n")
+			writeSampleComment(&b, "#", 0, n.comment)
+			writeSampleEntry(&b, "#", 0, n, " =")
+		}
+	case ConfigINI:
+		writeSampleComment(&b, ";", 0, p.message("config.sample", p.Name))
+		for _, n := range root.children {
+			if n.children == nil {
+				b.WriteString("\n")
+				writeSampleINI(&b, n)
+			}
+		}
+		for _, n := range root.children {
+			if n.children != nil {
+				fmt.Fprintf(&b, "\n[%s]\n", n.name)
+				for _, c := range n.children {
+					writeSampleINI(&b, c)
+				}
+			}
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// sampleValues returns the default value of the given flag, formatted for a
+// sample config in the given format, where values of slice flags are one
+// value per element in INI, and otherwise one sequence.
+func sampleValues(flag *pflag.Flag, format ConfigFormat) []string {
+	t := strings.TrimSuffix(flag.Value.Type(), "Slice")
+	raw := format == ConfigINI || t == "bool" || t == "count" ||
+		strings.HasPrefix(t, "int") || strings.HasPrefix(t, "uint") || strings.HasPrefix(t, "float")
+	quote := func(s string) string {
+		if raw {
+			return s
+		}
+		return strconv.Quote(s)
+	}
+
+	if _, ok := flag.Value.(pflag.SliceValue); !ok {
+		return []string{quote(flag.DefValue)}
+	}
+	values := splitDefValue(flag.DefValue)
+	for i := range values {
+		values[i] = quote(values[i])
+	}
+	if format == ConfigINI {
+		return values
+	}
+	return []string{"[" + strings.Join(values, ", ") + "]"}
+}
+
+// writeSampleComment writes comment, prefixing each of its lines with the
+// comment character c, indented by indent spaces.
+func writeSampleComment(b *strings.Builder, c string, indent int, comment string) {
+	for _, line := range strings.Split(comment, "\n") {
+		fmt.Fprintf(b, "%*s%s\n", indent, "", strings.TrimRight(c+" "+line, " "))
+	}
+}
+
+// writeSampleEntry writes the entry of n, using the given separator between
+// its name and value, and commented out using the comment character c if it is
+// disabled or has no value.
+func writeSampleEntry(b *strings.Builder, c string, indent int, n *sampleNode, sep string) {
+	prefix := ""
+	if n.disabled || len(n.values) == 0 {
+		prefix = c + " "
+	}
+	if len(n.values) == 0 {
+		fmt.Fprintf(b, "%*s%s%s%s\n", indent, "", prefix, n.name, sep)
+	}
+	for _, v := range n.values {
+		fmt.Fprintf(b, "%*s%s%s%s %s\n", indent, "", prefix, n.name, sep, v)
+	}
+}
+
+func writeSampleINI(b *strings.Builder, n *sampleNode) {
+	writeSampleComment(b, ";", 0, n.comment)
+	writeSampleEntry(b, ";", 0, n, " =")
+}
+
+func writeSampleYAML(b *strings.Builder, nodes []*sampleNode, indent int) {
+	for _, n := range nodes {
+		if indent == 0 {
+			b.WriteString("\n")
+		}
+		if n.children != nil {
+			if n.enabled() {
+				fmt.Fprintf(b, "%*s%s:\n", indent, "", n.name)
+			} else {
+				fmt.Fprintf(b, "%*s# %s:\n", indent, "", n.name)
+			}
+			writeSampleYAML(b, n.children, indent+2)
+		} else {
+			writeSampleComment(b, "#", indent, n.comment)
+			writeSampleEntry(b, "#", indent, n, ":")
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func newSampleParser(a *string, b *int, c *[]string) *ArgParser {
+	p := NewArgParser("testprog")
+	p.StringVar(a, "a-test", "x", "usage-a")
+	p.StringAllowOptions(a, "a-test", []string{"x", "y"})
+	p.IntVar(b, "b-test", 1, "usage-b")
+	p.ConfigKey("b-test", "log.b")
+	p.StringSliceVar(c, "c-test", []string{"p,q", "r"}, "usage-c")
+	p.ConfigKey("c-test", "log.c")
+	var d string
+	p.StringVar(&d, "d-test", "s3cr3t", "usage-d")
+	p.SetDefaultText("d-test", "")
+	p.ConfigKey("d-test", "secret.d")
+	var e int
+	p.IntVar(&e, "e-test", 0, "usage-e\n\nsecond paragraph")
+	p.SetDefaultText("e-test", "auto")
+	var f bool
+	p.BoolVar(&f, "f-test", false, "usage-f")
+	p.MarkHidden("f-test")
+	return p
+}
+
+func TestGenSampleConfig(t *testing.T) {
+	var a string
+	var b int
+	var c []string
+	p := newSampleParser(&a, &b, &c)
+	var sample strings.Builder
+	testNoError(t, p.GenSampleConfig(&sample, ConfigYAML))
+	expected := `# sample configuration for testprog
+
+# usage-a (one of: x, y)
+a-test: "x"
+
+log:
+  # usage-b
+  b: 1
+  # usage-c
+  c: ["p,q", "r"]
+
+# secret:
+  # usage-d
+  # d:
+
+# usage-e
+#
+# second paragraph
+# e-test: auto
+`
+	if sample.String() != expected {
+		t.Fatalf("expected sample:\n%s\ngot:\n%s", expected, sample.String())
+	}
+
+	sample.Reset()
+	testNoError(t, p.GenSampleConfig(&sample, ConfigTOML))
+	expected = `# sample configuration for testprog
+
+# usage-a (one of: x, y)
+a-test = "x"
+
+# usage-b
+log.b = 1
+
+# usage-c
+log.c = ["p,q", "r"]
+
+# usage-d
+# secret.d =
+
+# usage-e
+#
+# second paragraph
+# e-test = auto
+`
+	if sample.String() != expected {
+		t.Fatalf("expected sample:\n%s\ngot:\n%s", expected, sample.String())
+	}
+
+	sample.Reset()
+	testNoError(t, p.GenSampleConfig(&sample, ConfigINI))
+	expected = `; sample configuration for testprog
+
+; usage-a (one of: x, y)
+a-test = x
+
+; usage-e
+;
+; second paragraph
+; e-test = auto
+
+[log]
+; usage-b
+b = 1
+; usage-c
+c = p,q
+c = r
+
+[secret]
+; usage-d
+; d =
+`
+	if sample.String() != expected {
+		t.Fatalf("expected sample:\n%s\ngot:\n%s", expected, sample.String())
+	}
+
+	for _, format := range []ConfigFormat{ConfigYAML, ConfigTOML, ConfigINI} {
+		var sample strings.Builder
+		testNoError(t, newSampleParser(&a, &b, &c).GenSampleConfig(&sample, format))
+		p := newSampleParser(&a, &b, &c)
+		p.ConfigFile(writeConfig(t, "config", sample.String()), format)
+		testNoError(t, p.ParseArgs([]string{}))
+		if a != "x" || b != 1 || !slices.Equal(c, []string{"p,q", "r"}) {
+			t.Fatalf("unexpected values: %q, %d, %q", a, b, c)
+		}
+	}
+}
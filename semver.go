@@ -0,0 +1,178 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Semver is a semantic version as specified by https://semver.org, e.g.
+// "1.2.3-rc.1+build.5". Use ParseSemver() to get the parsed form of a value
+// accepted by StringAllowSemver().
+type Semver struct {
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	Prerelease string
+	Build      string
+}
+
+// The regular expression suggested by the semver 2.0.0 specification.
+var semverRegexp = regexp.MustCompile(
+	`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+		`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+		`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`,
+)
+
+// ParseSemver parses the given semantic version.
+func ParseSemver(s string) (Semver, error) {
+	m := semverRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return Semver{}, fmt.Errorf("%q is not a semantic version", s)
+	}
+	var v Semver
+	var err error
+	for i, n := range []*uint64{&v.Major, &v.Minor, &v.Patch} {
+		if *n, err = strconv.ParseUint(m[i+1], 10, 64); err != nil {
+			return Semver{}, fmt.Errorf("%q is not a semantic version: %w", s, err)
+		}
+	}
+	v.Prerelease = m[4]
+	v.Build = m[5]
+	return v, nil
+}
+
+// Compare returns -1, 0 or 1 depending on whether v has lower, equal or higher
+// precedence than other. Build metadata does not affect precedence.
+func (v Semver) Compare(other Semver) int {
+	for _, n := range [][2]uint64{
+		{v.Major, other.Major}, {v.Minor, other.Minor}, {v.Patch, other.Patch},
+	} {
+		if n[0] != n[1] {
+			if n[0] < n[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return comparePrerelease(v.Prerelease, other.Prerelease)
+}
+
+// String returns the version in its canonical string form.
+func (v Semver) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// semverConstraint is a comparison such as ">=1.2.0" which a version must
+// satisfy.
+type semverConstraint struct {
+	op      string
+	version Semver
+}
+
+func parseSemverConstraint(s string) (semverConstraint, error) {
+	s = strings.TrimSpace(s)
+	for _, op := range []string{">=", "<=", "!=", ">", "<", "="} {
+		if strings.HasPrefix(s, op) {
+			v, err := ParseSemver(strings.TrimSpace(s[len(op):]))
+			if err != nil {
+				return semverConstraint{}, err
+			}
+			return semverConstraint{op, v}, nil
+		}
+	}
+	v, err := ParseSemver(s)
+	if err != nil {
+		return semverConstraint{}, err
+	}
+	return semverConstraint{"=", v}, nil
+}
+
+func parseSemverConstraints(constraints []string) ([]semverConstraint, error) {
+	var parsed []semverConstraint
+	for _, c := range constraints {
+		sc, err := parseSemverConstraint(c)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, sc)
+	}
+	return parsed, nil
+}
+
+func (c semverConstraint) match(v Semver) bool {
+	cmp := v.Compare(c.version)
+	switch c.op {
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	}
+	return cmp == 0
+}
+
+func (c semverConstraint) String() string {
+	return c.op + c.version.String()
+}
+
+func comparePrerelease(a, b string) int {
+	if a == b {
+		return 0
+	}
+	// A version without pre-release has higher precedence.
+	if a == "" {
+		return 1
+	}
+	if b == "" {
+		return -1
+	}
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] == bs[i] {
+			continue
+		}
+		an, aErr := strconv.ParseUint(as[i], 10, 64)
+		bn, bErr := strconv.ParseUint(bs[i], 10, 64)
+		switch {
+		case aErr == nil && bErr == nil:
+			if an < bn {
+				return -1
+			}
+			return 1
+		case aErr == nil:
+			// Numeric identifiers have lower precedence than alphanumeric.
+			return -1
+		case bErr == nil:
+			return 1
+		case as[i] < bs[i]:
+			return -1
+		default:
+			return 1
+		}
+	}
+	if len(as) < len(bs) {
+		return -1
+	} else if len(as) > len(bs) {
+		return 1
+	}
+	return 0
+}
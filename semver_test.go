@@ -0,0 +1,66 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"testing"
+)
+
+func TestParseSemverFail(t *testing.T) {
+	for _, s := range []string{"1.2", "v1.2.3", "01.2.3", "1.2.3-", "1.2.3+"} {
+		if _, err := ParseSemver(s); err == nil {
+			t.Fatalf("%q: expected parsing error", s)
+		}
+	}
+}
+
+func TestParseSemverOK(t *testing.T) {
+	v, err := ParseSemver("1.22.3-rc.1+build.5")
+	testNoError(t, err)
+	expected := Semver{1, 22, 3, "rc.1", "build.5"}
+	if v != expected {
+		t.Fatalf("expected %+v, got: %+v", expected, v)
+	}
+	if v.String() != "1.22.3-rc.1+build.5" {
+		t.Fatalf("expected string '1.22.3-rc.1+build.5', got: %q", v.String())
+	}
+}
+
+func TestSemverCompare(t *testing.T) {
+	// Ordered by precedence, from the semver specification.
+	versions := []string{
+		"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta",
+		"1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.1.0", "2.0.0",
+	}
+	for i := 1; i < len(versions); i++ {
+		a, _ := ParseSemver(versions[i-1])
+		b, _ := ParseSemver(versions[i])
+		if a.Compare(b) != -1 || b.Compare(a) != 1 {
+			t.Fatalf("expected %s < %s", a, b)
+		}
+	}
+}
+
+func TestStringAllowSemverFail(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "", "usage-a")
+	p.StringAllowSemver(&a, "a-test", ">=1.2.0", "<2.0.0")
+	args := []string{"-a", "2.0.0"}
+	err := p.ParseArgs(args)
+	testError(t, err, "a-test: invalid value: \"2.0.0\" is not matching version constraint \"<2.0.0\"")
+}
+
+func TestStringAllowSemverOK(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "", "usage-a")
+	p.StringAllowSemver(&a, "a-test", ">=1.2.0", "<2.0.0")
+	args := []string{"-a", "1.10.0"}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
+}
@@ -0,0 +1,244 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"encoding/csv"
+	"slices"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// Source is an origin of flag values.
+type Source int
+
+const (
+	// SourceDefault is the flag's default value.
+	SourceDefault Source = iota
+	// SourceFlag is the command line.
+	SourceFlag
+	// SourceEnv is environment variables, see BindEnv() and AutoEnv().
+	SourceEnv
+	// SourceConfig is config files, see ConfigFile().
+	SourceConfig
+	// SourceCustom is the value sources added using AddSource().
+	SourceCustom
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourceDefault:
+		return "default"
+	case SourceFlag:
+		return "flag"
+	case SourceEnv:
+		return "env"
+	case SourceConfig:
+		return "config"
+	case SourceCustom:
+		return "custom"
+	}
+	return "unknown"
+}
+
+// ValueSource is a custom source of flag values, such as a remote
+// configuration service, see AddSource().
+type ValueSource interface {
+	// Lookup returns the value of the given flag, in the form it is given
+	// on the command line, and whether the source supplies one.
+	Lookup(name string) (string, bool)
+}
+
+// defaultPrecedence is the precedence of the sources unless set using
+// SetPrecedence().
+var defaultPrecedence = []Source{SourceFlag, SourceEnv, SourceConfig, SourceCustom}
+
+// AddSource adds a custom source of flag values, consulted by ParseArgs()
+// for the flags not given by a source of higher precedence, see
+// SetPrecedence(). Sources added earlier take precedence.
+func (p *ArgParser) AddSource(source ValueSource) {
+	if source == nil {
+		p.die("add source: cannot be defined with nil source")
+	}
+	if p.Parsed() {
+		p.die("add source: cannot define post-parse")
+	}
+	p.customSources = append(p.customSources, source)
+}
+
+// AllSources returns the source of each flag's value, see Source().
+func (p *ArgParser) AllSources() map[string]Source {
+	sources := map[string]Source{}
+	p.VisitAll(func(flag *pflag.Flag) {
+		sources[flag.Name] = p.Source(flag.Name)
+	})
+	return sources
+}
+
+// SetPrecedence sets the order in which the sources of flag values take
+// precedence, highest first. The sources not given follow in their default
+// order, which is SourceFlag, SourceEnv, SourceConfig, SourceCustom. A flag's
+// value is taken from the first source supplying one, and otherwise its
+// default is kept.
+func (p *ArgParser) SetPrecedence(sources ...Source) {
+	for i, source := range sources {
+		if !slices.Contains(defaultPrecedence, source) {
+			p.die("set precedence: %v: unknown source: %v", sources, source)
+		}
+		if slices.Contains(sources[:i], source) {
+			p.die("set precedence: %v: duplicate source: %v", sources, source)
+		}
+	}
+	if p.Parsed() {
+		p.die("set precedence: cannot define post-parse")
+	}
+	p.precedence = slices.Clone(sources)
+	for _, source := range defaultPrecedence {
+		if !slices.Contains(p.precedence, source) {
+			p.precedence = append(p.precedence, source)
+		}
+	}
+}
+
+// parseSources sets the flags from the sources, in order of precedence,
+// recording the source of each flag that is set.
+func (p *ArgParser) parseSources() error {
+	p.valueSources = map[string]Source{}
+	precedence := p.precedence
+	if precedence == nil {
+		precedence = defaultPrecedence
+	}
+	for _, source := range precedence {
+		var err error
+		switch source {
+		case SourceFlag:
+			p.VisitAll(func(flag *pflag.Flag) {
+				if _, ok := p.valueSources[flag.Name]; !ok && flag.Changed {
+					p.valueSources[flag.Name] = SourceFlag
+				}
+			})
+		case SourceEnv:
+			err = p.parseEnv()
+		case SourceConfig:
+			err = p.parseConfig()
+		case SourceCustom:
+			err = p.parseCustomSources()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Source returns the source of the given argument's value after ParseArgs(),
+// where SourceDefault means that no source supplied one. Positional arguments
+// are from SourceFlag when given.
+func (p *ArgParser) Source(name string) Source {
+	for _, pos := range p.pos {
+		if pos.name == name {
+			if p.Parsed() {
+				return SourceFlag
+			}
+			return SourceDefault
+		}
+	}
+	if p.posN != nil && p.posN.name == name {
+		if p.Parsed() && len(*p.posN.target) > 0 {
+			return SourceFlag
+		}
+		return SourceDefault
+	}
+	if flag := p.Lookup(name); flag == nil {
+		p.die("source: undefined flag: %s", name)
+	}
+	if source, ok := p.valueSources[name]; ok {
+		return source
+	}
+	return SourceDefault
+}
+
+// parseCustomSources sets the flags not set by a source of higher precedence
+// from the custom sources.
+func (p *ArgParser) parseCustomSources() error {
+	var err error
+	p.VisitAll(func(flag *pflag.Flag) {
+		if _, ok := p.valueSources[flag.Name]; err != nil || ok {
+			return
+		}
+		for _, source := range p.customSources {
+			if v, ok := source.Lookup(flag.Name); ok {
+				if e := p.setValue(flag, SourceCustom, v); e != nil {
+					err = p.errorf("error.source", e)
+				}
+				return
+			}
+		}
+	})
+	return err
+}
+
+// setValue sets the given flag to the given value, replacing any previous
+// value, also for slice flags, which otherwise append to it once set.
+func (p *ArgParser) setValue(flag *pflag.Flag, source Source, v string) error {
+	if sv, ok := flag.Value.(pflag.SliceValue); ok {
+		if err := sv.Replace([]string{}); err != nil {
+			return err
+		}
+	}
+	if err := p.Set(flag.Name, v); err != nil {
+		return err
+	}
+	p.valueSources[flag.Name] = source
+	return nil
+}
+
+// setValues sets the given flag to the given values, like setValue(), where
+// multiple values are only accepted by slice flags.
+func (p *ArgParser) setValues(flag *pflag.Flag, source Source, values []string) error {
+	if len(values) == 0 {
+		return nil
+	}
+	if flag.Value.Type() == "stringArray" {
+		for i, v := range values {
+			if i == 0 {
+				if err := p.setValue(flag, source, v); err != nil {
+					return err
+				}
+			} else if err := p.Set(flag.Name, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if _, ok := flag.Value.(pflag.SliceValue); !ok {
+		if len(values) > 1 {
+			return p.errorf("error.multiple-values", flag.Name)
+		}
+		return p.setValue(flag, source, values[0])
+	}
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	if err := w.Write(values); err != nil {
+		return err
+	}
+	w.Flush()
+	return p.setValue(flag, source, strings.TrimSuffix(b.String(), "\n"))
+}
+
+// splitDefValue splits the default value of a slice flag, as formatted by
+// pflag, into its elements.
+func splitDefValue(s string) []string {
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	if s == "" {
+		return []string{}
+	}
+	values, err := csv.NewReader(strings.NewReader(s)).Read()
+	if err != nil {
+		return []string{s}
+	}
+	return values
+}
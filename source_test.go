@@ -0,0 +1,110 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+type mapSource map[string]string
+
+func (m mapSource) Lookup(name string) (string, bool) {
+	v, ok := m[name]
+	return v, ok
+}
+
+func TestAddSource(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVar(&a, "a-test", "", "usage-a")
+	p.Required("a-test")
+	var b []string
+	p.StringSliceVar(&b, "b-test", nil, "usage-b")
+	var c string
+	p.StringVar(&c, "c-test", "", "usage-c")
+	p.BindEnv("c-test", "TESTPROG_C")
+	t.Setenv("TESTPROG_C", "env-c")
+	p.AddSource(mapSource{"a-test": "custom-a", "b-test": "x,y"})
+	p.AddSource(mapSource{"a-test": "custom2-a", "c-test": "custom2-c"})
+	testNoError(t, p.ParseArgs([]string{}))
+	if a != "custom-a" || c != "env-c" || !slices.Equal(b, []string{"x", "y"}) {
+		t.Fatalf("unexpected values: %q, %q, %q", a, b, c)
+	}
+
+	p = NewArgParser("testprog")
+	var d int
+	p.IntVar(&d, "d-test", 0, "usage-d")
+	p.AddSource(mapSource{"d-test": "x"})
+	testError(
+		t, p.ParseArgs([]string{}),
+		`value source: invalid argument "x" for "--d-test" flag: `+
+			`strconv.ParseInt: parsing "x": invalid syntax`,
+	)
+}
+
+func TestSetPrecedence(t *testing.T) {
+	p := NewArgParser("testprog")
+	p.SetPrecedence(SourceConfig, SourceEnv, SourceFlag)
+
+	var a string
+	p.StringVar(&a, "a-test", "", "usage-a")
+	p.BindEnv("a-test", "TESTPROG_A")
+	var b []string
+	p.StringSliceVar(&b, "b-test", nil, "usage-b")
+	p.BindEnv("b-test", "TESTPROG_B")
+	var c string
+	p.StringVar(&c, "c-test", "", "usage-c")
+	p.BindEnv("c-test", "TESTPROG_C")
+	t.Setenv("TESTPROG_A", "env-a")
+	t.Setenv("TESTPROG_B", "env-b1,env-b2")
+	t.Setenv("TESTPROG_C", "env-c")
+	p.ConfigFile(writeConfig(t, "config.yaml", "a-test: config-a\n"), ConfigYAML)
+	args := []string{"--a-test=arg-a", "--b-test=arg-b", "--c-test=arg-c"}
+	testNoError(t, p.ParseArgs(args))
+	if a != "config-a" || c != "env-c" {
+		t.Fatalf("unexpected values: %q, %q", a, c)
+	}
+	if !slices.Equal(b, []string{"env-b1", "env-b2"}) {
+		t.Fatalf("unexpected values: %q", b)
+	}
+}
+
+func TestSource(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVar(&a, "a-test", "", "usage-a")
+	var b string
+	p.StringVar(&b, "b-test", "", "usage-b")
+	p.BindEnv("b-test", "TESTPROG_B")
+	var c string
+	p.StringVar(&c, "c-test", "", "usage-c")
+	var d string
+	p.StringVar(&d, "d-test", "", "usage-d")
+	var e []string
+	p.StringPosNVar(&e, "e", "usage-e", 0, -1)
+	t.Setenv("TESTPROG_B", "env-b")
+	p.ConfigFile(writeConfig(t, "config.yaml", "c-test: config-c\n"), ConfigYAML)
+	if p.Source("a-test") != SourceDefault || p.Source("e") != SourceDefault {
+		t.Fatalf("unexpected sources before parsing: %v", p.AllSources())
+	}
+	testNoError(t, p.ParseArgs([]string{"--a-test=arg-a", "e1"}))
+	expected := map[string]Source{
+		"a-test": SourceFlag,
+		"b-test": SourceEnv,
+		"c-test": SourceConfig,
+		"d-test": SourceDefault,
+		"help":   SourceDefault,
+	}
+	if sources := p.AllSources(); !maps.Equal(sources, expected) {
+		t.Fatalf("expected sources %v, got %v", expected, sources)
+	}
+	if p.Source("e") != SourceFlag {
+		t.Fatalf("unexpected source of positional argument: %v", p.Source("e"))
+	}
+}
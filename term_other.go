@@ -0,0 +1,17 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package argparse
+
+import (
+	"os"
+)
+
+// terminalWidth returns 0, as terminal detection is not supported on this
+// platform.
+func terminalWidth(f *os.File) int {
+	return 0
+}
@@ -0,0 +1,271 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var tomlBareKeyRegexp = regexp.MustCompile(`^[0-9A-Za-z_-]+$`)
+
+var tomlBoolRegexp = regexp.MustCompile(`^(true|false)$`)
+
+var tomlDateTimeRegexp = regexp.MustCompile(
+	`^(\d{4}-\d{2}-\d{2}([Tt ]\d{2}:\d{2}:\d{2}(\.\d+)?([Zz]|[+-]\d{2}:\d{2})?)?|` +
+		`\d{2}:\d{2}:\d{2}(\.\d+)?)$`,
+)
+
+var tomlNumberRegexp = regexp.MustCompile(
+	`^([+-]?(0|[1-9](_?\d)*)(\.\d(_?\d)*)?([eE][+-]?\d(_?\d)*)?|[+-]?(inf|nan)|` +
+		`0x[0-9A-Fa-f](_?[0-9A-Fa-f])*|0o[0-7](_?[0-7])*|0b[01](_?[01])*)$`,
+)
+
+// parseTOML parses the TOML subset described by ConfigTOML into values keyed
+// by the table and dotted keys joined using ".".
+func parseTOML(data []byte) (map[string][]string, error) {
+	values := map[string][]string{}
+	tables := map[string]bool{} // defined by headers
+	dotted := map[string]bool{} // defined by dotted keys
+	prefix := ""
+	lines := strings.Split(string(data), "\n")
+	for i := 0; i < len(lines); i++ {
+		n := i + 1
+		line := strings.TrimSpace(cutTOML(lines[i], '#'))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[[") {
+			return nil, fmt.Errorf("line %d: unsupported array of tables: %s", n, line)
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: unterminated table header: %s", n, line)
+			}
+			parts, err := parseTOMLKey(line[1 : len(line)-1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			table := strings.Join(parts, ".")
+			_, isValue := values[table]
+			if isValue || tables[table] || dotted[table] || tomlValueTable(values, table) {
+				return nil, fmt.Errorf("line %d: duplicate table: %s", n, table)
+			}
+			tables[table] = true
+			prefix = table + "."
+			continue
+		}
+
+		k := cutTOML(line, '=')
+		if k == line {
+			return nil, fmt.Errorf("line %d: expected key and value separated by \"=\"", n)
+		}
+		parts, err := parseTOMLKey(k)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		key := prefix + strings.Join(parts, ".")
+		if _, ok := values[key]; ok || tables[key] || dotted[key] || tomlValueTable(values, key) {
+			return nil, fmt.Errorf("line %d: duplicate key: %s", n, key)
+		}
+		for j := 1; j < len(parts); j++ {
+			dotted[prefix+strings.Join(parts[:j], ".")] = true
+		}
+		v := strings.TrimSpace(line[len(k)+1:])
+		for strings.HasPrefix(v, "[") && cutTOML(v[1:], ']') == v[1:] && i+1 < len(lines) {
+			i++
+			v += " " + strings.TrimSpace(cutTOML(lines[i], '#'))
+		}
+		vs, err := parseTOMLValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		values[key] = vs
+	}
+	return values, nil
+}
+
+// cutTOML returns s up to the first occurrence of c that is not quoted, or
+// all of s.
+func cutTOML(s string, c byte) string {
+	quote := byte(0)
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote == '"' && s[i] == '\\':
+			i++
+		case quote != 0:
+			if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '"' || s[i] == '\'':
+			quote = s[i]
+		case s[i] == c:
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// parseTOMLBasicString parses a basic string, i.e. a double-quoted string
+// with TOML's escape sequences.
+func parseTOMLBasicString(s string) (string, error) {
+	invalid := fmt.Errorf("invalid basic string: %s", s)
+	if len(s) < 2 || !strings.HasSuffix(s, `"`) {
+		return "", invalid
+	}
+	var b strings.Builder
+	body := s[1 : len(s)-1]
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case c == '"' || (c < 0x20 && c != '\t') || c == 0x7f:
+			return "", invalid
+		case c != '\\':
+			b.WriteByte(c)
+		case i+1 == len(body):
+			return "", invalid
+		default:
+			i++
+			switch body[i] {
+			case 'b':
+				b.WriteByte('\b')
+			case 't':
+				b.WriteByte('\t')
+			case 'n':
+				b.WriteByte('\n')
+			case 'f':
+				b.WriteByte('\f')
+			case 'r':
+				b.WriteByte('\r')
+			case '"', '\\':
+				b.WriteByte(body[i])
+			case 'u', 'U':
+				size := 4
+				if body[i] == 'U' {
+					size = 8
+				}
+				if i+size >= len(body) {
+					return "", invalid
+				}
+				r, err := strconv.ParseUint(body[i+1:i+1+size], 16, 32)
+				if err != nil || !utf8.ValidRune(rune(r)) {
+					return "", invalid
+				}
+				b.WriteRune(rune(r))
+				i += size
+			default:
+				return "", invalid
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+// parseTOMLKey parses a bare, quoted or dotted key into its parts.
+func parseTOMLKey(s string) ([]string, error) {
+	var parts []string
+	for {
+		part := cutTOML(s, '.')
+		p := strings.TrimSpace(part)
+		switch {
+		case strings.HasPrefix(p, `"`) || strings.HasPrefix(p, "'"):
+			v, err := parseTOMLScalar(p)
+			if err != nil {
+				return nil, err
+			}
+			p = v
+		case !tomlBareKeyRegexp.MatchString(p):
+			return nil, fmt.Errorf("invalid key: %s", strings.TrimSpace(s))
+		}
+		parts = append(parts, p)
+		if part == s {
+			return parts, nil
+		}
+		s = s[len(part)+1:]
+	}
+}
+
+// parseTOMLScalar parses a basic or literal string, or a boolean, number,
+// date or time, which is returned as written, without underscores.
+func parseTOMLScalar(s string) (string, error) {
+	switch {
+	case s == "":
+		return "", fmt.Errorf("missing value")
+	case strings.HasPrefix(s, `"""`) || strings.HasPrefix(s, "'''"):
+		return "", fmt.Errorf("unsupported multi-line string: %s", s)
+	case strings.HasPrefix(s, `"`):
+		return parseTOMLBasicString(s)
+	case strings.HasPrefix(s, "'"):
+		v := s[1:]
+		if !strings.HasSuffix(v, "'") || strings.ContainsFunc(v[:len(v)-1], func(r rune) bool {
+			return r == '\'' || (r < 0x20 && r != '\t') || r == 0x7f
+		}) {
+			return "", fmt.Errorf("invalid literal string: %s", s)
+		}
+		return v[:len(v)-1], nil
+	case strings.ContainsRune("[{", rune(s[0])):
+		return "", fmt.Errorf("unsupported value: %s", s)
+	case tomlNumberRegexp.MatchString(s):
+		return strings.ReplaceAll(s, "_", ""), nil
+	case tomlBoolRegexp.MatchString(s) || tomlDateTimeRegexp.MatchString(s):
+		return s, nil
+	}
+	return "", fmt.Errorf("invalid value: %s", s)
+}
+
+// parseTOMLValue parses a value, either a scalar or an array of scalars.
+func parseTOMLValue(s string) ([]string, error) {
+	if !strings.HasPrefix(s, "[") {
+		v, err := parseTOMLScalar(s)
+		if err != nil {
+			return nil, err
+		}
+		return []string{v}, nil
+	}
+	items := cutTOML(s[1:], ']')
+	if items == s[1:] {
+		return nil, fmt.Errorf("unterminated array: %s", s)
+	}
+	if strings.TrimSpace(s[len(items)+2:]) != "" {
+		return nil, fmt.Errorf("unexpected text after array: %s", s)
+	}
+	values := []string{}
+	s = items
+	for s != "" {
+		item := cutTOML(s, ',')
+		if v := strings.TrimSpace(item); v != "" || item != s {
+			v, err := parseTOMLScalar(v)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		if item == s {
+			break
+		}
+		s = s[len(item)+1:]
+		if strings.TrimSpace(s) == "" {
+			break
+		}
+	}
+	return values, nil
+}
+
+// tomlValueTable returns whether a table of key, i.e. one of its dotted
+// prefixes, is already a key with a value.
+func tomlValueTable(values map[string][]string, key string) bool {
+	for i := range key {
+		if key[i] == '.' {
+			if _, ok := values[key[:i]]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestParseTOMLFail(t *testing.T) {
+	for _, data := range []string{
+		"a",
+		"a = ",
+		"a = 1\na = 2",
+		"[a]\nb = 1\n[a]\nb = 2",
+		"[[a]]",
+		"[a",
+		"a b = 1",
+		"a = {b = 1}",
+		"a = [1, 2",
+		"a = \"b",
+		"a = \"\"\"b\"\"\"",
+		"a = [1, , 2]",
+		"a = 'x' 'y'",
+		"a = 'x''y'",
+		"a = \"x\" \"y\"",
+		"a = \"x\" y",
+		"a = \"\\x41\"",
+		"a = \"\\u12\"",
+		"a = \"\\uD800\"",
+		"a = \"x\x01\"",
+		"a = 'x\x01'",
+		"a = hello",
+		"a = 1 2",
+		"a = True",
+		"a = 01",
+		"a = 1__0",
+		"a = _1",
+		"a = 1_",
+		"a = 1.",
+		"a = .5",
+		"a = 0xg",
+		"a = 2024-1-1",
+		"a = [1] 2",
+		"a = 1\na.b = 2",
+		"a.b = 1\na = 2",
+		"a = 1\n[a]",
+		"a.b = 1\n[a]",
+		"[a]\n[a]",
+	} {
+		if _, err := parseTOML([]byte(data)); err == nil {
+			t.Fatalf("expected error for %q", data)
+		}
+	}
+}
+
+func TestParseTOMLOK(t *testing.T) {
+	data := `# comment
+a = 1_000  # comment
+b.c = "x # y"
+"d.e" = 'C:\path'
+f = [1, "2, 3", '4',]
+g = [
+  "h",  # comment
+  "i\tj",
+]
+k = true
+q = [0xdead_beef, 0o17, 0b1, -1e-3, +inf, nan, 1_000.5]
+r = [1979-05-27T07:32:00-08:00, 1979-05-27, 07:32:00]
+s = ["\u00e9\U0001F600", "\"\\\b\f\n\r", 'a "b" \n']
+t = [
+  'x]',
+]
+
+[x.y]
+z = 1
+
+[x]
+w = 2
+
+[l]
+m = 1.5
+n.o = "p"
+`
+	values, err := parseTOML([]byte(data))
+	testNoError(t, err)
+	expected := map[string][]string{
+		"a":     {"1000"},
+		"b.c":   {"x # y"},
+		"d.e":   {`C:\path`},
+		"f":     {"1", "2, 3", "4"},
+		"g":     {"h", "i\tj"},
+		"k":     {"true"},
+		"q":     {"0xdeadbeef", "0o17", "0b1", "-1e-3", "+inf", "nan", "1000.5"},
+		"r":     {"1979-05-27T07:32:00-08:00", "1979-05-27", "07:32:00"},
+		"s":     {"\u00e9\U0001F600", "\"\\\b\f\n\r", `a "b" \n`},
+		"t":     {"x]"},
+		"x.w":   {"2"},
+		"x.y.z": {"1"},
+		"l.m":   {"1.5"},
+		"l.n.o": {"p"},
+	}
+	if !maps.EqualFunc(values, expected, slices.Equal) {
+		t.Fatalf("expected %q, got %q", expected, values)
+	}
+}
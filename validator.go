@@ -0,0 +1,280 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// Validator checks an argument value, returning an error describing why the
+// value is invalid. Validators are attached to arguments using Attach() or
+// AllowFunc(), and can be combined using And(), Or() and Not().
+type Validator func(string) error
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Validator{
+		"creatable":     AllowCreatable(),
+		"existing-dir":  AllowExistingDir(),
+		"existing-file": AllowExistingFile(),
+		"host-port":     AllowHostPort(),
+		"hostname":      AllowHostname(),
+		"port":          AllowPort(0),
+		"readable":      AllowReadable(),
+		"semver":        AllowSemver(),
+		"time":          AllowTime(),
+		"url":           AllowURL(),
+		"uuid":          AllowUUID(),
+		"writable":      AllowWritable(),
+	}
+)
+
+type attachedValidator struct {
+	name      string
+	validator Validator
+}
+
+// And returns a Validator accepting values accepted by all the given
+// validators. The error of the first failing validator is returned.
+func And(validators ...Validator) Validator {
+	return func(v string) error {
+		for _, validator := range validators {
+			if err := validator(v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// Not returns a Validator accepting values rejected by the given validator.
+func Not(validator Validator) Validator {
+	return func(v string) error {
+		if validator(v) == nil {
+			return valueError("value.not-allowed", v)
+		}
+		return nil
+	}
+}
+
+// Or returns a Validator accepting values accepted by any of the given
+// validators. If all fail, their errors are joined into one.
+func Or(validators ...Validator) Validator {
+	return func(v string) error {
+		var errs error
+		for _, validator := range validators {
+			err := validator(v)
+			if err == nil {
+				return nil
+			}
+			if errs == nil {
+				errs = err
+			} else {
+				errs = valueError("value.or", errs, err)
+			}
+		}
+		if errs == nil {
+			return valueError("value.not-allowed", v)
+		}
+		return errs
+	}
+}
+
+// LookupValidator returns the Validator registered with the given name, either
+// built in or added using RegisterValidator().
+func LookupValidator(name string) (Validator, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	validator, ok := registry[name]
+	return validator, ok
+}
+
+// RegisterValidator makes a Validator available by the given name, e.g. for
+// AttachNamed(). The built-in names are creatable, existing-dir,
+// existing-file, host-port, hostname, port, readable, semver, time, url, uuid
+// and writable. It panics if the name is already registered.
+func RegisterValidator(name string, validator Validator) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if name == "" {
+		panic("register validator: cannot be registered with empty name")
+	}
+	if validator == nil {
+		panic(fmt.Sprintf("register validator: %s: cannot be registered with nil validator", name))
+	}
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("register validator: %s: already registered", name))
+	}
+	registry[name] = validator
+}
+
+// AllowCreatable returns the Validator used by StringAllowCreatable().
+func AllowCreatable() Validator {
+	return checkCreatable
+}
+
+// AllowExistingDir returns the Validator used by StringAllowExistingDir().
+func AllowExistingDir() Validator {
+	return checkExistingDir
+}
+
+// AllowExistingFile returns the Validator used by StringAllowExistingFile().
+func AllowExistingFile() Validator {
+	return checkExistingFile
+}
+
+// AllowHostPort returns the Validator used by StringAllowHostPort().
+func AllowHostPort() Validator {
+	return checkHostPort
+}
+
+// AllowHostname returns the Validator used by StringAllowHostname().
+func AllowHostname() Validator {
+	return checkHostname
+}
+
+// AllowOptions returns a Validator accepting values among the given options,
+// as StringAllowOptions().
+func AllowOptions(options ...string) Validator {
+	return func(v string) error {
+		return checkOptions(v, options)
+	}
+}
+
+// AllowPort returns the Validator used by StringAllowPort().
+func AllowPort(opts PortOption) Validator {
+	return func(v string) error {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return valueError("value.not-port-number", v)
+		}
+		return checkPort(n, opts)
+	}
+}
+
+// AllowReadable returns the Validator used by StringAllowReadable().
+func AllowReadable() Validator {
+	return checkReadable
+}
+
+// AllowRegexp returns a Validator accepting values matching the given regular
+// expression, as StringAllowRegexp(). It panics if re cannot be compiled.
+func AllowRegexp(re string) Validator {
+	rec := regexp.MustCompile(re)
+	return func(v string) error {
+		if !rec.MatchString(v) {
+			return valueError("value.regexp", v, rec)
+		}
+		return nil
+	}
+}
+
+// AllowSemver returns the Validator used by StringAllowSemver(). It panics if
+// any of the constraints is invalid.
+func AllowSemver(constraints ...string) Validator {
+	parsed, err := parseSemverConstraints(constraints)
+	if err != nil {
+		panic(fmt.Sprintf("semver validator: invalid constraint: %v", err))
+	}
+	return func(v string) error {
+		return checkSemver(v, parsed)
+	}
+}
+
+// AllowTime returns the Validator used by StringAllowTime().
+func AllowTime(layouts ...string) Validator {
+	if len(layouts) == 0 {
+		layouts = defaultTimeLayouts
+	}
+	return func(v string) error {
+		return checkTime(v, layouts)
+	}
+}
+
+// AllowURL returns the Validator used by StringAllowURL().
+func AllowURL(schemes ...string) Validator {
+	return func(v string) error {
+		return checkURL(v, schemes)
+	}
+}
+
+// AllowUUID returns the Validator used by StringAllowUUID().
+func AllowUUID(versions ...int) Validator {
+	return func(v string) error {
+		return checkUUID(v, versions)
+	}
+}
+
+// AllowWritable returns the Validator used by StringAllowWritable().
+func AllowWritable() Validator {
+	return checkWritable
+}
+
+// Attach defines that the given argument's value is accepted by the given
+// validator. Contrary to AllowFunc(), the argument may be a flag of any type,
+// in which case its value is validated in its string form. For the varying
+// positional argument, each of its values is validated. Enforced with
+// ParseArgs().
+func (p *ArgParser) Attach(name string, validator Validator) {
+	if name == "" {
+		p.die("attach: cannot be defined with empty name")
+	}
+	if validator == nil {
+		p.die("attach: %s: cannot be defined with nil validator", name)
+	}
+	isPos := slices.ContainsFunc(p.pos, func(pos pos) bool { return pos.name == name })
+	if !isPos && (p.posN == nil || p.posN.name != name) {
+		if flag := p.Lookup(name); flag == nil {
+			p.die("attach: undefined flag: %s", name)
+		}
+		if p.Parsed() {
+			p.die("attach: %s: cannot define post-parse", name)
+		}
+	}
+	p.attached = append(p.attached, attachedValidator{name, validator})
+}
+
+// AttachNamed is like Attach(), but uses the Validator registered with the
+// given validator name, see RegisterValidator().
+func (p *ArgParser) AttachNamed(name, validatorName string) {
+	validator, ok := LookupValidator(validatorName)
+	if !ok {
+		p.die("attach: %s: unknown validator: %s", name, validatorName)
+	}
+	p.Attach(name, validator)
+}
+
+func (p *ArgParser) parseAttached() error {
+	errs := p.checkErrors()
+	for _, a := range p.attached {
+		for _, v := range p.valueStrings(a.name) {
+			if err := a.validator(v); err != nil {
+				if errs.add(fmt.Errorf("%s: %w", a.name, p.localize(err))) {
+					return errs.err()
+				}
+			}
+		}
+	}
+	return errs.err()
+}
+
+// valueStrings returns the values of the given positional argument or flag in
+// string form, which is one value unless it is the varying positional
+// argument.
+func (p *ArgParser) valueStrings(name string) []string {
+	for _, pos := range p.pos {
+		if pos.name == name {
+			return []string{*pos.target}
+		}
+	}
+	if p.posN != nil && p.posN.name == name {
+		return *p.posN.target
+	}
+	return []string{p.Lookup(name).Value.String()}
+}
@@ -0,0 +1,85 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAttachFail(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "", "usage-a")
+	p.Attach("a-test", Or(AllowRegexp("^x"), AllowOptions("test1", "test2")))
+	args := []string{"-a", "test3"}
+	err := p.ParseArgs(args)
+	testError(t, err, "a-test: invalid value: \"test3\" is not matching regexp \"^x\", or invalid value: \"test3\" is not among options: [\"test1\" \"test2\"]")
+}
+
+func TestAttachFailInt(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a int
+	p.IntVarP(&a, "a-test", "a", 80, "usage-a")
+	p.Attach("a-test", And(AllowPort(0), Not(AllowOptions("22"))))
+	args := []string{"-a", "22"}
+	err := p.ParseArgs(args)
+	testError(t, err, "a-test: invalid value: \"22\" is not allowed")
+}
+
+func TestAttachFailPosN(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a []string
+	p.StringPosNVar(&a, "a", "usage-a", 0, -1)
+	p.Attach("a", AllowRegexp("^x"))
+	args := []string{"x1", "y2", "x3"}
+	err := p.ParseArgs(args)
+	testError(t, err, "a: invalid value: \"y2\" is not matching regexp \"^x\"")
+}
+
+func TestAttachNamedFail(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "", "usage-a")
+	p.AttachNamed("a-test", "uuid")
+	args := []string{"-a", "test"}
+	err := p.ParseArgs(args)
+	testError(t, err, "a-test: invalid value: \"test\" is not a UUID")
+}
+
+func TestAttachNamedOK(t *testing.T) {
+	RegisterValidator("test-even-length", func(v string) error {
+		if len(v)%2 != 0 {
+			return errors.New("odd length")
+		}
+		return nil
+	})
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "", "usage-a")
+	p.AttachNamed("a-test", "test-even-length")
+	args := []string{"-a", "ab"}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
+}
+
+func TestAttachOK(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringPosVar(&a, "a", "usage-a")
+	p.Attach("a", Or(AllowRegexp("^x"), AllowOptions("test1", "test2")))
+	var b []string
+	p.StringPosNVar(&b, "b", "usage-b", 0, -1)
+	p.Attach("b", AllowRegexp("^x"))
+	args := []string{"xyz", "x1", "x2"}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
+}
@@ -0,0 +1,453 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"errors"
+	"io/fs"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PortOption modifies which port numbers are accepted by IntAllowPort and
+// StringAllowPort. Options are combined using bitwise OR.
+type PortOption int
+
+const (
+	// PortAllowZero accepts port 0, commonly meaning any available port.
+	PortAllowZero PortOption = 1 << iota
+	// PortDenyPrivileged rejects the privileged ports 1 to 1023.
+	PortDenyPrivileged
+)
+
+// Float64AllowNonNegative defines that the given float64 argument's value is
+// zero or greater. Enforced with ParseArgs().
+func (p *ArgParser) Float64AllowNonNegative(target *float64, name string) {
+	p.checkAllowTarget("allow non-negative", name, "float64")
+	fn := func(v float64) error {
+		if !(v >= 0) {
+			return valueError("value.non-negative", v)
+		}
+		return nil
+	}
+	p.allowedFloatFuncs = append(
+		p.allowedFloatFuncs, allowedFloat64Func{name, target, fn, "non-negative"},
+	)
+}
+
+// Float64AllowPositive defines that the given float64 argument's value is
+// greater than zero. Enforced with ParseArgs().
+func (p *ArgParser) Float64AllowPositive(target *float64, name string) {
+	p.checkAllowTarget("allow positive", name, "float64")
+	fn := func(v float64) error {
+		if !(v > 0) {
+			return valueError("value.positive", v)
+		}
+		return nil
+	}
+	p.allowedFloatFuncs = append(
+		p.allowedFloatFuncs, allowedFloat64Func{name, target, fn, "positive"},
+	)
+}
+
+// IntAllowNonNegative defines that the given int argument's value is zero or
+// greater. Enforced with ParseArgs().
+func (p *ArgParser) IntAllowNonNegative(target *int, name string) {
+	p.checkAllowTarget("allow non-negative", name, "int")
+	p.allowedIntFuncs = append(p.allowedIntFuncs, allowedIntFunc{name, target, func(v int) error {
+		if v < 0 {
+			return valueError("value.non-negative", v)
+		}
+		return nil
+	}, "non-negative"})
+}
+
+// IntAllowPositive defines that the given int argument's value is greater than
+// zero. Enforced with ParseArgs().
+func (p *ArgParser) IntAllowPositive(target *int, name string) {
+	p.checkAllowTarget("allow positive", name, "int")
+	p.allowedIntFuncs = append(p.allowedIntFuncs, allowedIntFunc{name, target, func(v int) error {
+		if v <= 0 {
+			return valueError("value.positive", v)
+		}
+		return nil
+	}, "positive"})
+}
+
+// IntAllowPort defines that the given int argument's value is a TCP/UDP port
+// number, i.e. 1 to 65535 unless modified by opts. Enforced with ParseArgs().
+func (p *ArgParser) IntAllowPort(target *int, name string, opts PortOption) {
+	p.checkAllowTarget("allow port", name, "int")
+	p.allowedIntFuncs = append(p.allowedIntFuncs, allowedIntFunc{name, target, func(v int) error {
+		return checkPort(v, opts)
+	}, ""})
+}
+
+// StringAllowCreatable defines that the given argument's value is a path that
+// can be written to, e.g. an output file. Either the path is a writable file,
+// or it does not exist and its parent directory is writable. Enforced with
+// ParseArgs().
+func (p *ArgParser) StringAllowCreatable(target *string, name string) {
+	p.checkAllowTarget("allow creatable", name, "string")
+	p.allowedFuncs = append(p.allowedFuncs, allowedFunc{name, target, checkCreatable})
+}
+
+// StringAllowExistingDir defines that the given argument's value is the path
+// of an existing directory. Enforced with ParseArgs().
+func (p *ArgParser) StringAllowExistingDir(target *string, name string) {
+	p.checkAllowTarget("allow existing dir", name, "string")
+	p.allowedFuncs = append(p.allowedFuncs, allowedFunc{name, target, checkExistingDir})
+}
+
+// StringAllowExistingFile defines that the given argument's value is the path
+// of an existing regular file. Enforced with ParseArgs().
+func (p *ArgParser) StringAllowExistingFile(target *string, name string) {
+	p.checkAllowTarget("allow existing file", name, "string")
+	p.allowedFuncs = append(p.allowedFuncs, allowedFunc{name, target, checkExistingFile})
+}
+
+// StringAllowHostPort defines that the given argument's value is a host and
+// port pair, e.g. "example.com:443" or "[::1]:80", where the host is an RFC
+// 1123 hostname or an IP address. Enforced with ParseArgs().
+func (p *ArgParser) StringAllowHostPort(target *string, name string) {
+	p.checkAllowTarget("allow host port", name, "string")
+	p.allowedFuncs = append(p.allowedFuncs, allowedFunc{name, target, checkHostPort})
+}
+
+// StringAllowHostname defines that the given argument's value is an RFC 1123
+// hostname, e.g. "www.example.com". Enforced with ParseArgs().
+func (p *ArgParser) StringAllowHostname(target *string, name string) {
+	p.checkAllowTarget("allow hostname", name, "string")
+	p.allowedFuncs = append(p.allowedFuncs, allowedFunc{name, target, checkHostname})
+}
+
+// StringAllowPort defines that the given argument's value is a TCP/UDP port
+// number, i.e. 1 to 65535 unless modified by opts. Enforced with ParseArgs().
+func (p *ArgParser) StringAllowPort(target *string, name string, opts PortOption) {
+	p.checkAllowTarget("allow port", name, "string")
+	p.allowedFuncs = append(p.allowedFuncs, allowedFunc{name, target, AllowPort(opts)})
+}
+
+// StringAllowReadable defines that the given argument's value is the path of
+// an existing file or directory which is readable. Enforced with ParseArgs().
+func (p *ArgParser) StringAllowReadable(target *string, name string) {
+	p.checkAllowTarget("allow readable", name, "string")
+	p.allowedFuncs = append(p.allowedFuncs, allowedFunc{name, target, checkReadable})
+}
+
+// StringAllowSemver defines that the given argument's value is a semantic
+// version, e.g. "1.2.3". If any constraints are given, such as ">=1.2.0" or
+// "<2.0.0", the version must satisfy all of them. Enforced with ParseArgs().
+func (p *ArgParser) StringAllowSemver(target *string, name string, constraints ...string) {
+	p.checkAllowTarget("allow semver", name, "string")
+	parsed, err := parseSemverConstraints(constraints)
+	if err != nil {
+		p.die("allow semver: %s: invalid constraint: %v", name, err)
+	}
+	p.allowedFuncs = append(p.allowedFuncs, allowedFunc{name, target, func(v string) error {
+		return checkSemver(v, parsed)
+	}})
+}
+
+// StringAllowTime defines that the given argument's value is a time matching
+// one of the given layouts, as used by time.Parse. If no layouts are given,
+// time.RFC3339 is used. Enforced with ParseArgs().
+func (p *ArgParser) StringAllowTime(target *string, name string, layouts ...string) {
+	p.checkAllowTarget("allow time", name, "string")
+	p.allowedFuncs = append(p.allowedFuncs, allowedFunc{name, target, AllowTime(layouts...)})
+}
+
+// StringAllowURL defines that the given argument's value is an absolute URL.
+// If any schemes are given, the URL's scheme must be among them. Enforced with
+// ParseArgs().
+func (p *ArgParser) StringAllowURL(target *string, name string, schemes []string) {
+	p.checkAllowTarget("allow url", name, "string")
+	p.allowedFuncs = append(p.allowedFuncs, allowedFunc{name, target, AllowURL(schemes...)})
+}
+
+// StringAllowUUID defines that the given argument's value is a UUID in the
+// RFC 4122 string format, e.g. "123e4567-e89b-12d3-a456-426614174000". If any
+// versions are given, the UUID must be of the RFC 4122 variant and one of the
+// versions. Enforced with ParseArgs().
+func (p *ArgParser) StringAllowUUID(target *string, name string, versions ...int) {
+	p.checkAllowTarget("allow uuid", name, "string")
+	for _, v := range versions {
+		if v < 1 || v > 15 {
+			p.die("allow uuid: %s: invalid version: %d", name, v)
+		}
+	}
+	p.allowedFuncs = append(p.allowedFuncs, allowedFunc{name, target, AllowUUID(versions...)})
+}
+
+// StringAllowWritable defines that the given argument's value is the path of
+// an existing file or directory which is writable. Enforced with ParseArgs().
+func (p *ArgParser) StringAllowWritable(target *string, name string) {
+	p.checkAllowTarget("allow writable", name, "string")
+	p.allowedFuncs = append(p.allowedFuncs, allowedFunc{name, target, checkWritable})
+}
+
+func checkCreatable(v string) error {
+	if _, err := os.Lstat(v); err == nil {
+		return checkWritable(v)
+	}
+	parent := filepath.Dir(v)
+	fi, err := os.Stat(parent)
+	if err != nil || !fi.IsDir() {
+		return valueError("value.not-creatable", v, parent)
+	}
+	if !dirWritable(parent) {
+		return valueError("value.parent-writable", v, parent)
+	}
+	return nil
+}
+
+func checkExistingDir(v string) error {
+	fi, err := statPath(v)
+	if err != nil {
+		return err
+	}
+	if fi.Mode().IsRegular() {
+		return valueError("value.dir-is-file", v)
+	}
+	if !fi.IsDir() {
+		return valueError("value.not-dir", v)
+	}
+	return nil
+}
+
+func checkExistingFile(v string) error {
+	fi, err := statPath(v)
+	if err != nil {
+		return err
+	}
+	if !fi.Mode().IsRegular() {
+		return valueError("value.not-file", v)
+	}
+	return nil
+}
+
+var hostnameLabelRegexp = regexp.MustCompile(`^[0-9a-zA-Z]([0-9a-zA-Z-]{0,61}[0-9a-zA-Z])?$`)
+
+func checkHostPort(v string) error {
+	host, port, err := net.SplitHostPort(v)
+	if err != nil {
+		return valueError("value.not-host-port", v)
+	}
+	if net.ParseIP(host) == nil && checkHostname(host) != nil {
+		return valueError("value.not-host", v, host)
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil || checkPort(n, 0) != nil {
+		return valueError("value.not-port", v, port)
+	}
+	return nil
+}
+
+func checkHostname(v string) error {
+	labels := strings.TrimSuffix(v, ".")
+	if labels == "" || len(labels) > 253 {
+		return valueError("value.not-hostname", v)
+	}
+	for _, label := range strings.Split(labels, ".") {
+		if !hostnameLabelRegexp.MatchString(label) {
+			return valueError("value.not-hostname", v)
+		}
+	}
+	return nil
+}
+
+// checkOptions checks that v is among options, suggesting the closest option
+// otherwise.
+func checkOptions(v string, options []string) error {
+	if slices.Contains(options, v) {
+		return nil
+	}
+	if option := suggestOption(v, options); option != "" {
+		return valueError("value.options-suggest", v, options, option)
+	}
+	return valueError("value.options", v, options)
+}
+
+func checkPort(v int, opts PortOption) error {
+	if v == 0 && opts&PortAllowZero != 0 {
+		return nil
+	}
+	lo := 1
+	if opts&PortDenyPrivileged != 0 {
+		lo = 1024
+	}
+	if v < lo || v > 65535 {
+		if opts&PortAllowZero != 0 {
+			return valueError("value.port-or-zero", v, lo, 65535)
+		}
+		return valueError("value.port", v, lo, 65535)
+	}
+	return nil
+}
+
+func checkReadable(v string) error {
+	if _, err := statPath(v); err != nil {
+		return err
+	}
+	if !accessible(v, accessRead) {
+		return valueError("value.not-readable", v)
+	}
+	return nil
+}
+
+func checkSemver(v string, constraints []semverConstraint) error {
+	sv, err := ParseSemver(v)
+	if err != nil {
+		return valueError("value.invalid", err)
+	}
+	for _, c := range constraints {
+		if !c.match(sv) {
+			return valueError("value.semver", v, c)
+		}
+	}
+	return nil
+}
+
+var defaultTimeLayouts = []string{time.RFC3339}
+
+func checkTime(v string, layouts []string) error {
+	for _, layout := range layouts {
+		if _, err := time.Parse(layout, v); err == nil {
+			return nil
+		}
+	}
+	return valueError("value.time", v, layouts)
+}
+
+func checkURL(v string, schemes []string) error {
+	u, err := url.Parse(v)
+	if err != nil {
+		return valueError("value.not-url", v, errors.Unwrap(err))
+	}
+	if u.Scheme == "" || (u.Host == "" && u.Opaque == "" && u.Path == "") {
+		return valueError("value.not-absolute", v)
+	}
+	if len(schemes) > 0 && !slices.ContainsFunc(schemes, func(s string) bool {
+		return strings.EqualFold(s, u.Scheme)
+	}) {
+		return valueError("value.url-scheme", v, u.Scheme, schemes)
+	}
+	return nil
+}
+
+var uuidRegexp = regexp.MustCompile(
+	`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`,
+)
+
+func checkUUID(v string, versions []int) error {
+	if !uuidRegexp.MatchString(v) {
+		return valueError("value.not-uuid", v)
+	}
+	if len(versions) == 0 {
+		return nil
+	}
+	if !strings.ContainsRune("89abAB", rune(v[19])) {
+		return valueError("value.uuid-variant", v)
+	}
+	version, _ := strconv.ParseInt(v[14:15], 16, 0)
+	if !slices.Contains(versions, int(version)) {
+		return valueError("value.uuid-version", v, version, versions)
+	}
+	return nil
+}
+
+func checkWritable(v string) error {
+	fi, err := statPath(v)
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		if !dirWritable(v) {
+			return valueError("value.not-writable", v)
+		}
+		return nil
+	}
+	if !accessible(v, accessWrite) {
+		return valueError("value.not-writable", v)
+	}
+	return nil
+}
+
+// The modes of accessible(), as used by access(2).
+const (
+	accessExec  uint32 = 1
+	accessWrite uint32 = 2
+	accessRead  uint32 = 4
+)
+
+// dirWritable checks if a file can be created in dir, i.e. whether it is
+// writable and searchable.
+func dirWritable(dir string) bool {
+	return accessible(dir, accessWrite|accessExec)
+}
+
+// editDistance returns the edit distance between a and b, counting
+// insertions, deletions, substitutions and transpositions of adjacent
+// characters.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+func statPath(v string) (fs.FileInfo, error) {
+	fi, err := os.Stat(v)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, valueError("value.not-exist", v)
+	} else if err != nil {
+		return nil, valueError("value.invalid", err)
+	}
+	return fi, nil
+}
+
+// suggestOption returns the option closest to v, ignoring case, if it is the
+// only one close enough to likely be intended, and otherwise "".
+func suggestOption(v string, options []string) string {
+	best, bestDistance, tied := "", 0, false
+	for i, option := range options {
+		d := editDistance(strings.ToLower(v), strings.ToLower(option))
+		if i == 0 || d < bestDistance {
+			best, bestDistance, tied = option, d, false
+		} else if d == bestDistance {
+			tied = true
+		}
+	}
+	n := len([]rune(v))
+	if tied || bestDistance >= n || bestDistance > max(1, n/3) {
+		return ""
+	}
+	return best
+}
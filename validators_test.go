@@ -0,0 +1,369 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFloat64AllowNonNegativeFail(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a float64
+	p.Float64VarP(&a, "a-test", "a", 1, "usage-a")
+	p.Float64AllowNonNegative(&a, "a-test")
+	args := []string{"-a", "-0.5"}
+	err := p.ParseArgs(args)
+	testError(t, err, "a-test: invalid value: -0.5 is not a non-negative number")
+}
+
+func TestFloat64AllowPositiveOK(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a float64
+	p.Float64VarP(&a, "a-test", "a", 1, "usage-a")
+	p.Float64AllowPositive(&a, "a-test")
+	args := []string{"-a", "0.5"}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
+}
+
+func TestIntAllowNonNegativeOK(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a int
+	p.IntVarP(&a, "a-test", "a", 1, "usage-a")
+	p.IntAllowNonNegative(&a, "a-test")
+	args := []string{"-a", "0"}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
+}
+
+func TestIntAllowPortFail(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a int
+	p.IntVarP(&a, "a-test", "a", 8080, "usage-a")
+	p.IntAllowPort(&a, "a-test", PortAllowZero|PortDenyPrivileged)
+	args := []string{"-a", "80"}
+	err := p.ParseArgs(args)
+	testError(t, err, "a-test: invalid value: 80 is not a valid port number, expected 0 or 1024 to 65535")
+}
+
+func TestIntAllowPortOK(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a int
+	p.IntVarP(&a, "a-test", "a", 8080, "usage-a")
+	p.IntAllowPort(&a, "a-test", PortAllowZero)
+	args := []string{"-a", "0"}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
+}
+
+func TestIntAllowPositiveFail(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a int
+	p.IntVarP(&a, "a-test", "a", 1, "usage-a")
+	p.IntAllowPositive(&a, "a-test")
+	args := []string{"-a", "0"}
+	err := p.ParseArgs(args)
+	testError(t, err, "a-test: invalid value: 0 is not a positive number")
+}
+
+func TestStringAllowCreatableFail(t *testing.T) {
+	p := NewArgParser("testprog")
+	path := filepath.Join(t.TempDir(), "missing", "file")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "", "usage-a")
+	p.StringAllowCreatable(&a, "a-test")
+	args := []string{"-a", path}
+	err := p.ParseArgs(args)
+	testError(t, err, "a-test: invalid value: \""+path+"\" cannot be created, parent directory \""+filepath.Dir(path)+"\" does not exist")
+}
+
+func TestStringAllowCreatableOK(t *testing.T) {
+	p := NewArgParser("testprog")
+	path := filepath.Join(t.TempDir(), "file")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "", "usage-a")
+	p.StringAllowCreatable(&a, "a-test")
+	args := []string{"-a", path}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
+}
+
+func TestStringAllowExistingDirFail(t *testing.T) {
+	p := NewArgParser("testprog")
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "", "usage-a")
+	p.StringAllowExistingDir(&a, "a-test")
+	args := []string{"-a", path}
+	err := p.ParseArgs(args)
+	testError(t, err, "a-test: invalid value: \""+path+"\" is a file, not a directory")
+}
+
+func TestStringAllowExistingDirOK(t *testing.T) {
+	p := NewArgParser("testprog")
+	dir := t.TempDir()
+
+	var a string
+	p.StringPosVar(&a, "a", "usage-a")
+	p.StringAllowExistingDir(&a, "a")
+	args := []string{dir}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
+}
+
+func TestStringAllowExistingFileFail(t *testing.T) {
+	p := NewArgParser("testprog")
+	dir := t.TempDir()
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "", "usage-a")
+	p.StringAllowExistingFile(&a, "a-test")
+	args := []string{"-a", dir}
+	err := p.ParseArgs(args)
+	testError(t, err, "a-test: invalid value: \""+dir+"\" is not a regular file")
+}
+
+func TestStringAllowExistingFileFailMissing(t *testing.T) {
+	p := NewArgParser("testprog")
+	path := filepath.Join(t.TempDir(), "missing")
+
+	var a string
+	p.StringPosVar(&a, "a", "usage-a")
+	p.StringAllowExistingFile(&a, "a")
+	args := []string{path}
+	err := p.ParseArgs(args)
+	testError(t, err, "a: invalid value: \""+path+"\" does not exist")
+}
+
+func TestStringAllowExistingFileOK(t *testing.T) {
+	p := NewArgParser("testprog")
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "", "usage-a")
+	p.StringAllowExistingFile(&a, "a-test")
+	args := []string{"-a", path}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
+}
+
+func TestStringAllowHostPortFail(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "", "usage-a")
+	p.StringAllowHostPort(&a, "a-test")
+	args := []string{"-a", "example.com:http"}
+	err := p.ParseArgs(args)
+	testError(t, err, "a-test: invalid value: \"example.com:http\" has invalid port \"http\"")
+}
+
+func TestStringAllowHostPortOK(t *testing.T) {
+	for _, v := range []string{"example.com:443", "[::1]:80", "127.0.0.1:8080"} {
+		p := NewArgParser("testprog")
+
+		var a string
+		p.StringVarP(&a, "a-test", "a", "", "usage-a")
+		p.StringAllowHostPort(&a, "a-test")
+		args := []string{"-a", v}
+		err := p.ParseArgs(args)
+		testNoError(t, err)
+	}
+}
+
+func TestStringAllowHostnameFail(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "", "usage-a")
+	p.StringAllowHostname(&a, "a-test")
+	args := []string{"-a", "-example.com"}
+	err := p.ParseArgs(args)
+	testError(t, err, "a-test: invalid value: \"-example.com\" is not a valid hostname")
+}
+
+func TestStringAllowHostnameOK(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "", "usage-a")
+	p.StringAllowHostname(&a, "a-test")
+	args := []string{"-a", "1st-host.example.com."}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
+}
+
+func TestStringAllowPortFail(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringPosVar(&a, "a", "usage-a")
+	p.StringAllowPort(&a, "a", 0)
+	args := []string{"65536"}
+	err := p.ParseArgs(args)
+	testError(t, err, "a: invalid value: 65536 is not a valid port number, expected 1 to 65535")
+}
+
+func TestStringAllowPortOK(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringPosVar(&a, "a", "usage-a")
+	p.StringAllowPort(&a, "a", 0)
+	args := []string{"443"}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
+}
+
+func TestStringAllowReadableFail(t *testing.T) {
+	p := NewArgParser("testprog")
+	path := filepath.Join(t.TempDir(), "missing")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "", "usage-a")
+	p.StringAllowReadable(&a, "a-test")
+	args := []string{"-a", path}
+	err := p.ParseArgs(args)
+	testError(t, err, "a-test: invalid value: \""+path+"\" does not exist")
+}
+
+func TestStringAllowReadableOK(t *testing.T) {
+	p := NewArgParser("testprog")
+	dir := t.TempDir()
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "", "usage-a")
+	p.StringAllowReadable(&a, "a-test")
+	args := []string{"-a", dir}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
+}
+
+func TestStringAllowTimeFail(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "", "usage-a")
+	p.StringAllowTime(&a, "a-test", time.DateOnly, time.DateTime)
+	args := []string{"-a", "2024-01-01T00:00:00Z"}
+	err := p.ParseArgs(args)
+	testError(t, err, "a-test: invalid value: \"2024-01-01T00:00:00Z\" is not matching any time format: [\"2006-01-02\" \"2006-01-02 15:04:05\"]")
+}
+
+func TestStringAllowTimeOK(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "", "usage-a")
+	p.StringAllowTime(&a, "a-test")
+	args := []string{"-a", "2024-01-01T00:00:00Z"}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
+}
+
+func TestStringAllowURLFail(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "", "usage-a")
+	p.StringAllowURL(&a, "a-test", []string{"https"})
+	args := []string{"-a", "http://example.com"}
+	err := p.ParseArgs(args)
+	testError(t, err, "a-test: invalid value: \"http://example.com\" has URL scheme \"http\", which is not among: [\"https\"]")
+}
+
+func TestStringAllowURLFailRelative(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "", "usage-a")
+	p.StringAllowURL(&a, "a-test", nil)
+	args := []string{"-a", "example.com/path"}
+	err := p.ParseArgs(args)
+	testError(t, err, "a-test: invalid value: \"example.com/path\" is not an absolute URL")
+}
+
+func TestStringAllowURLOK(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "", "usage-a")
+	p.StringAllowURL(&a, "a-test", []string{"http", "https"})
+	args := []string{"-a", "HTTPS://example.com/path"}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
+}
+
+func TestStringAllowUUIDFail(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "", "usage-a")
+	p.StringAllowUUID(&a, "a-test")
+	args := []string{"-a", "123e4567-e89b-12d3-a456-42661417400"}
+	err := p.ParseArgs(args)
+	testError(t, err, "a-test: invalid value: \"123e4567-e89b-12d3-a456-42661417400\" is not a UUID")
+}
+
+func TestStringAllowUUIDFailVersion(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "", "usage-a")
+	p.StringAllowUUID(&a, "a-test", 4, 7)
+	args := []string{"-a", "123e4567-e89b-12d3-a456-426614174000"}
+	err := p.ParseArgs(args)
+	testError(t, err, "a-test: invalid value: \"123e4567-e89b-12d3-a456-426614174000\" is a version 1 UUID, expected version: [4 7]")
+}
+
+func TestStringAllowUUIDOK(t *testing.T) {
+	p := NewArgParser("testprog")
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "", "usage-a")
+	p.StringAllowUUID(&a, "a-test", 4)
+	args := []string{"-a", "0F8FAD5B-D9CB-469F-A165-70867728950E"}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
+}
+
+func TestStringAllowWritableOK(t *testing.T) {
+	p := NewArgParser("testprog")
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var a string
+	p.StringVarP(&a, "a-test", "a", "", "usage-a")
+	p.StringAllowWritable(&a, "a-test")
+	args := []string{"-a", path}
+	err := p.ParseArgs(args)
+	testNoError(t, err)
+
+	dir := filepath.Dir(path)
+	testNoError(t, p.ParseArgs([]string{"-a", dir}))
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) != 1 {
+		t.Fatalf("unexpected entries: %v, %v", entries, err)
+	}
+}
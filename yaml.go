@@ -0,0 +1,209 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type yamlLevel struct {
+	indent int
+	prefix string
+}
+
+// parseYAML parses the YAML subset described by ConfigYAML into values keyed
+// by the nested keys joined using ".". Null values are omitted.
+func parseYAML(data []byte) (map[string][]string, error) {
+	values := map[string][]string{}
+	levels := []yamlLevel{{0, ""}}
+	pending, pendingIndent := "", 0
+	seq, seqIndent := "", 0
+	started := false
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(stripYAMLComment(line), " \t\r")
+		content := strings.TrimLeft(line, " ")
+		if content == "" {
+			continue
+		}
+		if line == "---" || strings.HasPrefix(line, "--- ") || line == "..." {
+			if started || line != "---" {
+				return nil, fmt.Errorf("line %d: multiple documents are not supported", i+1)
+			}
+			started = true
+			continue
+		}
+		started = true
+		indent := len(line) - len(content)
+		if content[0] == '\t' {
+			return nil, fmt.Errorf("line %d: tabs cannot be used for indentation", i+1)
+		}
+
+		if item, ok := strings.CutPrefix(content, "-"); ok && (item == "" || item[0] == ' ') {
+			if pending != "" && indent >= pendingIndent {
+				seq, seqIndent, pending = pending, indent, ""
+			} else if seq == "" || indent != seqIndent {
+				return nil, fmt.Errorf("line %d: unexpected sequence item", i+1)
+			}
+			v, err := parseYAMLScalar(strings.TrimSpace(item))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			values[seq] = append(values[seq], v)
+			continue
+		}
+		seq = ""
+
+		if pending != "" && indent > pendingIndent {
+			levels = append(levels, yamlLevel{indent, pending + "."})
+		}
+		pending = ""
+		for len(levels) > 1 && levels[len(levels)-1].indent > indent {
+			levels = levels[:len(levels)-1]
+		}
+		level := levels[len(levels)-1]
+		if level.indent != indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", i+1)
+		}
+
+		key, value, ok := cutYAMLKey(content)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key and value separated by \": \"", i+1)
+		}
+		key, err := parseYAMLScalar(key)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		key = level.prefix + key
+		if _, ok := values[key]; ok {
+			return nil, fmt.Errorf("line %d: duplicate key: %s", i+1, key)
+		}
+		if value == "" {
+			pending, pendingIndent = key, indent
+			continue
+		}
+		vs, err := parseYAMLValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		if vs != nil {
+			values[key] = vs
+		}
+	}
+	return values, nil
+}
+
+// cutYAMLKey splits a mapping entry into its key and value, where the key is
+// terminated by ":" followed by whitespace or the end of the line.
+func cutYAMLKey(s string) (key, value string, found bool) {
+	quote := byte(0)
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote == '"' && s[i] == '\\':
+			i++
+		case quote != 0:
+			if s[i] == quote {
+				quote = 0
+			}
+		case i == 0 && (s[i] == '"' || s[i] == '\''):
+			quote = s[i]
+		case s[i] == ':' && (i+1 == len(s) || s[i+1] == ' '):
+			return strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// parseYAMLScalar parses a plain, single-quoted or double-quoted scalar. A
+// plain scalar cannot contain ": " or end with ":", as it would be a mapping.
+func parseYAMLScalar(s string) (string, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		v, err := strconv.Unquote(s)
+		if err != nil {
+			return "", fmt.Errorf("invalid double-quoted scalar: %s", s)
+		}
+		return v, nil
+	case strings.HasPrefix(s, "'"):
+		if len(s) < 2 || !strings.HasSuffix(s, "'") {
+			return "", fmt.Errorf("invalid single-quoted scalar: %s", s)
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	case s != "" && strings.ContainsRune("&*!|>{%@`", rune(s[0])):
+		return "", fmt.Errorf("unsupported value: %s", s)
+	case strings.Contains(s, ": ") || strings.HasSuffix(s, ":"):
+		return "", fmt.Errorf("invalid plain scalar: %s", s)
+	}
+	return s, nil
+}
+
+// parseYAMLValue parses the value of a mapping entry, either a scalar or a
+// flow sequence of scalars, returning nil for null.
+func parseYAMLValue(s string) ([]string, error) {
+	if s == "~" || s == "null" || s == "Null" || s == "NULL" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(s, "[") {
+		v, err := parseYAMLScalar(s)
+		if err != nil {
+			return nil, err
+		}
+		return []string{v}, nil
+	}
+	if !strings.HasSuffix(s, "]") {
+		return nil, fmt.Errorf("unterminated flow sequence: %s", s)
+	}
+	var values []string
+	inner := strings.TrimSpace(s[1 : len(s)-1])
+	if inner == "" {
+		return []string{}, nil
+	}
+	quote := byte(0)
+	start := 0
+	for i := 0; i <= len(inner); i++ {
+		switch {
+		case i == len(inner) || (quote == 0 && inner[i] == ','):
+			v, err := parseYAMLScalar(strings.TrimSpace(inner[start:i]))
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+			start = i + 1
+		case quote == '"' && inner[i] == '\\':
+			i++
+		case quote != 0:
+			if inner[i] == quote {
+				quote = 0
+			}
+		case inner[i] == '"' || inner[i] == '\'':
+			quote = inner[i]
+		}
+	}
+	return values, nil
+}
+
+// stripYAMLComment removes a comment, i.e. "#" at the start of the line or
+// preceded by whitespace, and the rest of the line, unless quoted.
+func stripYAMLComment(line string) string {
+	quote := byte(0)
+	for i := 0; i < len(line); i++ {
+		switch {
+		case quote == '"' && line[i] == '\\':
+			i++
+		case quote != 0:
+			if line[i] == quote {
+				quote = 0
+			}
+		case line[i] == '"' || line[i] == '\'':
+			if i == 0 || strings.ContainsRune(" \t[,:-", rune(line[i-1])) {
+				quote = line[i]
+			}
+		case line[i] == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
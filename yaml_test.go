@@ -0,0 +1,70 @@
+// SPDX-FileCopyrightText: 2024 Philip Eklöf
+//
+// SPDX-License-Identifier: MIT
+
+package argparse
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestParseYAMLFail(t *testing.T) {
+	for _, data := range []string{
+		"a",
+		"a: 1\n  b: 2",
+		"a:\n  b: 1\n c: 2",
+		"a: 1\na: 2",
+		"- a",
+		"a: |\n  text",
+		"a: {b: 1}",
+		"a: [1, 2",
+		"a: \"b",
+		"\ta: 1",
+		"a: 1\n---\nb: 2",
+		"---\na: 1\n---\nb: 2",
+		"a: 1\n...",
+		"--- a: 1",
+		"a: b: c",
+		"a: b:",
+		"a:\n- b: c",
+		"a: [b: c]",
+	} {
+		if _, err := parseYAML([]byte(data)); err == nil {
+			t.Fatalf("expected error for %q", data)
+		}
+	}
+}
+
+func TestParseYAMLOK(t *testing.T) {
+	data := `---
+# comment
+a: 1  # comment
+b:
+  c: "x # y"
+  d:
+    e: 'it''s'
+f: [1, "2, 3", '4']
+g:
+- h
+- "i\tj"
+k: ~
+l: "a\"b"
+m: http://example.com/#x
+`
+	values, err := parseYAML([]byte(data))
+	testNoError(t, err)
+	expected := map[string][]string{
+		"a":     {"1"},
+		"b.c":   {"x # y"},
+		"b.d.e": {"it's"},
+		"f":     {"1", "2, 3", "4"},
+		"g":     {"h", "i\tj"},
+		"l":     {`a"b`},
+		"m":     {"http://example.com/#x"},
+	}
+	if !maps.EqualFunc(values, expected, slices.Equal) {
+		t.Fatalf("expected %q, got %q", expected, values)
+	}
+}